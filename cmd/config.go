@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/ifosch/synthetic/pkg/command"
+	"github.com/ifosch/synthetic/pkg/script"
+)
+
+// config is the configuration of the bot, read from the YAML file in
+// the CONFIG_FILE environment variable.
+type config struct {
+	// Access lists the policies granting access to the
+	// commands. Everybody can run any command when it's empty.
+	Access []command.Policy `yaml:"access"`
+	// ConfirmJobs lists the patterns of the Jenkins jobs whose
+	// builds must be confirmed, like `deploy-*`.
+	ConfirmJobs []string `yaml:"confirm_jobs"`
+	// RateLimits limits how often the users can run commands.
+	RateLimits command.RateLimits `yaml:"rate_limits"`
+	// Audit configures the audit log of the commands run.
+	Audit struct {
+		File string `yaml:"file"`
+		// Admins are the users and user groups allowed to query
+		// the audit log.
+		Admins []string `yaml:"admins"`
+	} `yaml:"audit"`
+	// Schedules configures the commands run periodically.
+	Schedules struct {
+		File string `yaml:"file"`
+	} `yaml:"schedules"`
+	// Aliases configures the aliases defined by the users.
+	Aliases struct {
+		File string `yaml:"file"`
+	} `yaml:"aliases"`
+	// Plugins configures the plugins providing more commands.
+	Plugins struct {
+		Dir string `yaml:"dir"`
+		// Env lists the environment variables given to the
+		// plugins, besides PATH and HOME.
+		Env []string `yaml:"env"`
+	} `yaml:"plugins"`
+	// Scripts are commands running executables.
+	Scripts []*script.Script `yaml:"scripts"`
+}
+
+// loadConfig reads and validates the configuration in `filename`.
+func loadConfig(filename string) (*config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &config{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filename, err)
+	}
+	for i := range cfg.Access {
+		if err := cfg.Access[i].Validate(); err != nil {
+			return nil, fmt.Errorf("error in %s: %w", filename, err)
+		}
+	}
+	if err := cfg.RateLimits.Validate(); err != nil {
+		return nil, fmt.Errorf("error in %s: %w", filename, err)
+	}
+	for _, pattern := range cfg.ConfirmJobs {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("error in %s: wrong pattern `%s` in confirm_jobs: %w", filename, pattern, err)
+		}
+	}
+	for _, pattern := range cfg.Audit.Admins {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("error in %s: wrong pattern `%s` in audit admins: %w", filename, pattern, err)
+		}
+	}
+	for _, s := range cfg.Scripts {
+		if err := s.Validate(); err != nil {
+			return nil, fmt.Errorf("error in %s: %w", filename, err)
+		}
+	}
+	return cfg, nil
+}
+
+// confirmJob reports whether building `job` must be confirmed.
+func (cfg *config) confirmJob(job string) bool {
+	for _, pattern := range cfg.ConfirmJobs {
+		if ok, _ := path.Match(pattern, job); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -1,16 +1,30 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/slack-go/slack"
 
 	"github.com/ifosch/synthetic/pkg/command"
 	jobcontrol "github.com/ifosch/synthetic/pkg/job_control"
 	"github.com/ifosch/synthetic/pkg/k8s"
+	"github.com/ifosch/synthetic/pkg/plugin"
+	"github.com/ifosch/synthetic/pkg/schedule"
 	myslack "github.com/ifosch/synthetic/pkg/slack"
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+// Categories of the commands in help.
+const (
+	categoryJenkins   = "jenkins"
+	categoryK8s       = "k8s"
+	categorySchedules = "schedules"
 )
 
 func main() {
@@ -37,18 +51,165 @@ func main() {
 		os.Getenv("JENKINS_USER"),
 		os.Getenv("JENKINS_PASSWORD"),
 	)
-	if err := jenkins.Connect(); err != nil {
+	if err := jenkins.Connect(context.Background()); err != nil {
 		log.Fatalf("error connecting to jenkins: %s", err.Error())
 	}
 
+	cfg := &config{}
+	if path, ok := os.LookupEnv("CONFIG_FILE"); ok {
+		var err error
+		cfg, err = loadConfig(path)
+		if err != nil {
+			log.Fatalf("error loading the configuration: %s", err.Error())
+		}
+	}
+
 	go chat.Start()
 	cHandler := command.NewHandler()
+	cHandler.SetRouting(command.FirstMatch)
+	useMiddlewares(cHandler, cfg)
+	if auditLog := openAuditLog(cHandler, cfg); auditLog != nil {
+		defer auditLog.Close()
+	}
+	loadAliases(cHandler, cfg)
+	cHandler.SetTimeout(time.Minute)
+	cHandler.SetConcurrency(20)
 	registerChatCommands(cHandler)
-	registerJenkinsCommands(cHandler, jenkins)
+	registerJenkinsCommands(cHandler, jenkins, cfg)
 	registerK8sCommands(cHandler)
+	registerScripts(cHandler, cfg)
+	for _, p := range loadPlugins(cHandler, cfg) {
+		defer p.Close()
+	}
+
+	ctx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	startScheduler(ctx, cHandler, chat, cfg)
+	timeout := shutdownTimeout()
+
+	done := make(chan struct{})
+	go func() {
+		cHandler.EventLoop(chat.MessageChannel)
+		close(done)
+	}()
+	go cHandler.ReactionLoop(chat.ReactionChannel)
+
+	// Blocks until chat.MessageChannel is closed or a signal to
+	// stop is received
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case <-done:
+	case sig := <-signals:
+		log.Printf("Received %v, shutting down", sig)
+		stopScheduler()
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := cHandler.Shutdown(ctx); err != nil {
+			log.Printf("Commands still running on shutdown: %s", err.Error())
+		}
+	}
+}
+
+// useMiddlewares sets up the middlewares of `handler`, with the access
+// policies and rate limits in `cfg`.
+func useMiddlewares(handler *command.Handler, cfg *config) {
+	handler.Use(command.Recovery(), command.Logging())
+	if len(cfg.Access) > 0 {
+		handler.Use(command.Authorization(cfg.Access))
+	} else {
+		log.Printf("No access policies configured, everybody can run any command")
+	}
+	handler.Use(command.RateLimit(cfg.RateLimits))
+}
+
+// openAuditLog sets the audit log in `cfg` to `handler`, if any, and
+// returns it to be closed on exit.
+func openAuditLog(handler *command.Handler, cfg *config) *command.AuditLog {
+	if cfg.Audit.File == "" {
+		return nil
+	}
+	auditLog, err := command.NewAuditLog(cfg.Audit.File)
+	if err != nil {
+		log.Fatalf("error opening the audit log: %s", err.Error())
+	}
+	handler.SetAuditLog(auditLog, cfg.Audit.Admins)
+	return auditLog
+}
+
+// loadAliases sets the aliases in the file in `cfg` to `handler`, if
+// any.
+func loadAliases(handler *command.Handler, cfg *config) {
+	if cfg.Aliases.File == "" {
+		return
+	}
+	aliases, err := command.NewAliases(cfg.Aliases.File)
+	if err != nil {
+		log.Fatalf("error loading the aliases: %s", err.Error())
+	}
+	handler.SetAliases(aliases)
+}
+
+func registerScripts(handler *command.Handler, cfg *config) {
+	for _, s := range cfg.Scripts {
+		if err := s.Register(handler); err != nil {
+			log.Fatalf("error registering the script %s: %s", s.Name, err.Error())
+		}
+	}
+}
 
-	// Blocks until chat.MessageChannel is closed
-	cHandler.EventLoop(chat.MessageChannel)
+// loadPlugins registers the commands of the plugins in the directory
+// in `cfg`, if any, and returns them to be closed on exit.
+func loadPlugins(handler *command.Handler, cfg *config) []*plugin.Plugin {
+	if cfg.Plugins.Dir == "" {
+		return nil
+	}
+	plugins, err := plugin.Load(cfg.Plugins.Dir, cfg.Plugins.Env)
+	if err != nil {
+		log.Fatalf("error loading the plugins: %s", err.Error())
+	}
+	for _, p := range plugins {
+		if err := p.Register(handler); err != nil {
+			log.Fatalf("error registering the plugin %s: %s", p.Name(), err.Error())
+		}
+		log.Printf("Loaded plugin %v", p.Name())
+	}
+	return plugins
+}
+
+// startScheduler registers the schedule commands in `handler` and runs
+// the commands scheduled in the file in `cfg` until `ctx` is done. It
+// does nothing without a file.
+func startScheduler(ctx context.Context, handler *command.Handler, chat *myslack.Chat, cfg *config) {
+	if cfg.Schedules.File == "" {
+		log.Printf("No schedules file configured, scheduling commands is disabled")
+		return
+	}
+	scheduler, err := schedule.NewScheduler(
+		cfg.Schedules.File,
+		chatPoster{chat},
+		scheduleChecker(handler, cfg.Access),
+		chat.MessageChannel,
+	)
+	if err != nil {
+		log.Fatalf("error loading the schedules: %s", err.Error())
+	}
+	registerScheduleCommands(handler, scheduler)
+	go scheduler.Run(ctx)
+}
+
+// shutdownTimeout returns how long to wait for the commands still
+// running on shutdown, from the SHUTDOWN_TIMEOUT environment variable.
+func shutdownTimeout() time.Duration {
+	value, ok := os.LookupEnv("SHUTDOWN_TIMEOUT")
+	if !ok {
+		return 8 * time.Second
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("wrong SHUTDOWN_TIMEOUT: %s", err.Error())
+	}
+	return timeout
 }
 
 func registerChatCommands(handler *command.Handler) {
@@ -56,7 +217,7 @@ func registerChatCommands(handler *command.Handler) {
 	// LogMessage is a message processor to log the message received.
 	err = handler.Register(
 		"main.LogMessage",
-		func(c *command.Command) {
+		func(c *command.Command) error {
 			msg := c.Message()
 			thread := ""
 			if msg.Thread() {
@@ -69,18 +230,25 @@ func registerChatCommands(handler *command.Handler) {
 				thread,
 				msg.Conversation().Name(),
 			)
+			return nil
 		},
 	)
 	if err != nil {
 		panic(err)
 	}
-	err = handler.Register(
+	err = handler.RegisterCommand(
 		"main.replyHello",
-		func(c *command.Command) {
+		command.Spec{
+			Verb:     "hello",
+			Args:     []command.Arg{{Name: "greeting", Variadic: true}},
+			Summary:  "Says hello back",
+			Examples: []string{"hello", "hello there"},
+			Category: command.CategoryChat,
+		},
+		func(c *command.Command) error {
 			msg := c.Message()
-			if msg.Mention() && strings.Contains(msg.Text(), "hello") {
-				msg.Reply("hello", msg.Thread())
-			}
+			msg.Reply("hello", msg.Thread())
+			return nil
 		},
 	)
 	if err != nil {
@@ -88,11 +256,12 @@ func registerChatCommands(handler *command.Handler) {
 	}
 	err = handler.Register(
 		"main.reactHello",
-		func(c *command.Command) {
+		func(c *command.Command) error {
 			msg := c.Message()
 			if !msg.Mention() && strings.Contains(msg.Text(), "hello") {
 				msg.React("wave")
 			}
+			return nil
 		},
 	)
 	if err != nil {
@@ -100,51 +269,168 @@ func registerChatCommands(handler *command.Handler) {
 	}
 }
 
-func registerJenkinsCommands(handler *command.Handler, jenkins *jobcontrol.Jenkins) {
+func registerJenkinsCommands(handler *command.Handler, jenkins *jobcontrol.Jenkins, cfg *config) {
 	var err error
-	err = handler.Register(
+	err = handler.RegisterCommand(
 		"jenkins.List",
-		func(c *command.Command) {
-			msg := c.Message()
-			if msg.Text() == "list" && msg.Mention() {
-				jenkins.List(msg)
-			}
+		command.Spec{
+			Verb:     "list",
+			Summary:  "Lists the Jenkins jobs",
+			Category: categoryJenkins,
+		},
+		func(c *command.Command) error {
+			jenkins.List(c.Message())
+			return nil
 		},
 	)
 	if err != nil {
 		panic(err)
 	}
-	err = handler.Register(
+	err = handler.RegisterCommand(
 		"jenkins.Describe",
-		func(c *command.Command) {
-			msg := c.Message()
-			if msg.Mention() && strings.Contains(msg.Text(), "describe") {
-				jenkins.Describe(msg)
-			}
+		command.Spec{
+			Verb:     "describe",
+			Args:     []command.Arg{{Name: "job", Required: true}},
+			Summary:  "Describes a Jenkins job and its parameters",
+			Examples: []string{"describe deploy"},
+			Category: categoryJenkins,
+		},
+		func(c *command.Command) error {
+			return jenkins.Describe(c.Message())
 		},
 	)
 	if err != nil {
 		panic(err)
 	}
-	err = handler.Register(
+	err = handler.RegisterCommand(
 		"jenkins.Build",
-		func(c *command.Command) {
-			msg := c.Message()
-			if msg.Mention() && strings.Contains(msg.Text(), "build") {
-				jenkins.Build(msg)
-			}
+		command.Spec{
+			Verb:          "build",
+			Args:          []command.Arg{{Name: "job", Required: true}},
+			AnyParams:     true,
+			Timeout:       2 * time.Hour,
+			MaxConcurrent: 5,
+			Confirm:       true,
+			ConfirmIf: func(c *command.Command) bool {
+				return cfg.confirmJob(c.Arg("job"))
+			},
+			// The failed builds are everyone's business.
+			PublicErrors: true,
+			Summary:      "Builds a Jenkins job with the given parameters",
+			Examples:     []string{"build deploy", "build deploy ENV=staging INDEX=\"users ducks\""},
+			Category:     categoryJenkins,
+		},
+		func(c *command.Command) error {
+			return jenkins.Build(c.Context(), c.Message(), c, c.Session())
 		},
 	)
 	if err != nil {
 		panic(err)
 	}
-	err = handler.Register(
+	err = handler.RegisterCommand(
 		"jenkins.Reload",
-		func(c *command.Command) {
-			msg := c.Message()
-			if msg.Mention() && strings.Contains(msg.Text(), "reload") {
-				jenkins.Reload(msg)
+		command.Spec{
+			Verb:     "reload",
+			Summary:  "Reloads the list of Jenkins jobs",
+			Category: categoryJenkins,
+		},
+		func(c *command.Command) error {
+			return jenkins.Reload(c.Context(), c.Message())
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// chatPoster posts the messages of a schedule.Scheduler in the chat.
+type chatPoster struct {
+	chat *myslack.Chat
+}
+
+func (p chatPoster) Post(conversationID, text string) (synthetic.Message, error) {
+	return p.chat.Post(conversationID, text)
+}
+
+func (p chatPoster) User(id string) (synthetic.User, error) {
+	return p.chat.User(id)
+}
+
+// scheduleChecker returns a schedule.Checker routing the scheduled
+// commands with `handler`, and authorizing them with `policies`, if
+// any.
+func scheduleChecker(handler *command.Handler, policies []command.Policy) schedule.Checker {
+	return func(msg synthetic.Message) error {
+		commands, err := handler.Route(msg)
+		if err != nil || len(policies) == 0 {
+			return err
+		}
+		for _, c := range commands {
+			if err := command.Authorize(policies, c); err != nil {
+				return err
 			}
+		}
+		return nil
+	}
+}
+
+func registerScheduleCommands(handler *command.Handler, scheduler *schedule.Scheduler) {
+	var err error
+	err = handler.RegisterCommand(
+		"schedule.Create",
+		command.Spec{
+			Verb: "schedule",
+			Args: []command.Arg{
+				{Name: "cron", Required: true},
+				{Name: "command", Required: true, Variadic: true},
+			},
+			AnyParams: true,
+			// The chain operators are part of the command to
+			// schedule, rather than chaining others now.
+			Unchained: true,
+			Summary:   "Runs a command periodically in this conversation",
+			Examples:  []string{"schedule \"0 9 * * 1-5\" build nightly-report", "schedule \"*/30 * * * *\" list pods"},
+			Category:  categorySchedules,
+		},
+		func(c *command.Command) error {
+			// The command is taken from the tokens, as its
+			// parameters and chain operators aren't part of the
+			// `command` argument.
+			text := c.Rest(2)
+			return scheduler.Create(c.Message(), c.Arg("cron"), text)
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+	err = handler.RegisterCommand(
+		"schedule.List",
+		command.Spec{
+			Verb:        "list",
+			Subcommands: []string{"schedules"},
+			Summary:     "Lists the scheduled commands",
+			Category:    categorySchedules,
+		},
+		func(c *command.Command) error {
+			scheduler.List(c.Message())
+			return nil
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+	err = handler.RegisterCommand(
+		"schedule.Delete",
+		command.Spec{
+			Verb:        "delete",
+			Subcommands: []string{"schedule"},
+			Args:        []command.Arg{{Name: "id", Required: true}},
+			Summary:     "Deletes a scheduled command",
+			Examples:    []string{"delete schedule 3"},
+			Category:    categorySchedules,
+		},
+		func(c *command.Command) error {
+			return scheduler.Delete(c.Message(), c.Arg("id"))
 		},
 	)
 	if err != nil {
@@ -154,25 +440,39 @@ func registerJenkinsCommands(handler *command.Handler, jenkins *jobcontrol.Jenki
 
 func registerK8sCommands(handler *command.Handler) {
 	var err error
-	err = handler.Register(
+	err = handler.RegisterCommand(
 		"k8s.listClusters",
-		func(c *command.Command) {
-			msg := c.Message()
-			if msg.Text() == "list clusters" && msg.Mention() {
-				k8s.ListClusters(msg)
-			}
+		command.Spec{
+			Verb:        "list",
+			Subcommands: []string{"clusters"},
+			Summary:     "Lists the Kubernetes clusters in the bot's kubeconfig",
+			Category:    categoryK8s,
+		},
+		func(c *command.Command) error {
+			return k8s.ListClusters(c.Message())
 		},
 	)
 	if err != nil {
 		panic(err)
 	}
-	err = handler.Register(
+	err = handler.RegisterCommand(
 		"k8s.listPods",
-		func(c *command.Command) {
-			msg := c.Message()
-			if msg.Mention() && strings.Contains(msg.Text(), "list pods") {
-				k8s.ListPods(msg)
-			}
+		command.Spec{
+			Verb:        "list",
+			Subcommands: []string{"pods"},
+			Args: []command.Arg{
+				{Name: "cluster"},
+				{Name: "namespace"},
+			},
+			// The busy namespaces have too many pods to read
+			// them in several messages.
+			Overflow: synthetic.OverflowSnippet,
+			Summary:  "Lists the pods of a Kubernetes cluster, optionally in a namespace",
+			Examples: []string{"list pods", "list pods cluster1.example.com kube-system"},
+			Category: categoryK8s,
+		},
+		func(c *command.Command) error {
+			return k8s.ListPods(c.Context(), c.Message(), c.Arg("cluster"), c.Arg("namespace"))
 		},
 	)
 	if err != nil {
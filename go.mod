@@ -4,7 +4,9 @@ go 1.16
 
 require (
 	github.com/bndr/gojenkins v1.1.0
-	github.com/slack-go/slack v0.9.4
+	github.com/google/go-cmp v0.5.8 // indirect
+	github.com/slack-go/slack v0.15.0
+	gopkg.in/yaml.v2 v2.4.0
 	k8s.io/api v0.22.2
 	k8s.io/apimachinery v0.22.2
 	k8s.io/client-go v0.22.2
@@ -0,0 +1,169 @@
+package command
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+// Policy grants some users, and the members of some user groups,
+// access to some commands. It can also restrict the Jenkins jobs, and
+// the Kubernetes clusters and namespaces those commands act on. All
+// the lists accept path.Match patterns like `deploy-*`, and an empty
+// list of jobs, clusters or namespaces doesn't restrict them.
+type Policy struct {
+	Name string `yaml:"name"`
+	// Users are matched against the user ID or name, like
+	// `U012AB3CD` or `@alice`, and Groups against the user groups,
+	// like `@sre`.
+	Users  []string `yaml:"users"`
+	Groups []string `yaml:"groups"`
+	// Commands are matched against the verb and subcommands of the
+	// commands, like `build` or `list pods`.
+	Commands   []string `yaml:"commands"`
+	Jobs       []string `yaml:"jobs"`
+	Clusters   []string `yaml:"clusters"`
+	Namespaces []string `yaml:"namespaces"`
+}
+
+// DeniedError is returned when no Policy allows a user to run a
+// command.
+type DeniedError struct {
+	User    string
+	Command string
+	Reason  string
+}
+
+func (e *DeniedError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("Sorry %s, you're not allowed to run `%s` %s", e.User, e.Command, e.Reason)
+	}
+	return fmt.Sprintf("Sorry %s, you're not allowed to run `%s`. Ask an admin if you need it", e.User, e.Command)
+}
+
+// Validate checks that all the patterns in the Policy are valid.
+func (p *Policy) Validate() error {
+	if len(p.Users) == 0 && len(p.Groups) == 0 {
+		return fmt.Errorf("policy `%s` has no users nor groups", p.Name)
+	}
+	if len(p.Commands) == 0 {
+		return fmt.Errorf("policy `%s` has no commands", p.Name)
+	}
+	lists := [][]string{p.Users, p.Groups, p.Commands, p.Jobs, p.Clusters, p.Namespaces}
+	for _, patterns := range lists {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("policy `%s` has a wrong pattern `%s`: %w", p.Name, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// resources returns the patterns restricting the values of each
+// argument.
+func (p *Policy) resources() map[string][]string {
+	return map[string][]string{
+		"job":       p.Jobs,
+		"cluster":   p.Clusters,
+		"namespace": p.Namespaces,
+	}
+}
+
+// appliesTo reports whether the Policy applies to `user`.
+func (p *Policy) appliesTo(user synthetic.User) bool {
+	if matchAny(p.Users, user.ID()) || matchAny(p.Users, user.Name()) {
+		return true
+	}
+	for _, group := range user.Groups() {
+		if matchAny(p.Groups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// allows returns nil if the Policy allows running `command`, or the
+// reason why it doesn't.
+func (p *Policy) allows(command *Command) *DeniedError {
+	words := strings.Join(command.Spec().Words(), " ")
+	denied := &DeniedError{
+		User:    command.Message().User().Name(),
+		Command: words,
+	}
+	if !matchAny(p.Commands, words) {
+		return denied
+	}
+	for _, arg := range command.Spec().Args {
+		patterns := p.resources()[arg.Name]
+		if len(patterns) == 0 {
+			continue
+		}
+		value := command.Arg(arg.Name)
+		if value == "" {
+			denied.Reason = fmt.Sprintf("without a `%s`", arg.Name)
+			return denied
+		}
+		if !matchAny(patterns, value) {
+			denied.Reason = fmt.Sprintf("on %s `%s`", arg.Name, value)
+			return denied
+		}
+	}
+	return nil
+}
+
+// Authorization is a Middleware only running the commands allowed by
+// some of the `policies` applying to the user sending them. Any other
+// command is answered with a *DeniedError.
+func Authorization(policies []Policy) Middleware {
+	return func(next ExecutorFunc) ExecutorFunc {
+		return func(c *Command) error {
+			if err := Authorize(policies, c); err != nil {
+				return err
+			}
+			return next(c)
+		}
+	}
+}
+
+// Authorize returns a *DeniedError unless some of the `policies`
+// applying to the user sending `command` allows it. The commands of
+// the synthetic.SystemUser are always allowed, as the rights of the
+// users they run on behalf of are checked when they're sent.
+func Authorize(policies []Policy, command *Command) error {
+	if command.Message().User().ID() == (synthetic.SystemUser{}).ID() {
+		return nil
+	}
+	denied := &DeniedError{
+		User:    command.Message().User().Name(),
+		Command: strings.Join(command.Spec().Words(), " "),
+	}
+	for i := range policies {
+		if !policies[i].appliesTo(command.Message().User()) {
+			continue
+		}
+		err := policies[i].allows(command)
+		if err == nil {
+			return nil
+		}
+		// Tell why a policy allowing the command denies its
+		// arguments, rather than a generic denial.
+		if err.Reason != "" {
+			denied = err
+		}
+	}
+	return denied
+}
+
+// matchAny reports whether `value` matches any of the glob
+// `patterns`.
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+func TestAuthorization(t *testing.T) {
+	disableLogs()
+	policies := []Policy{
+		{
+			Name:     "everyone",
+			Users:    []string{"*"},
+			Commands: []string{"help", "list jobs"},
+		},
+		{
+			Name:     "developers",
+			Groups:   []string{"@developers"},
+			Commands: []string{"build"},
+			Jobs:     []string{"test-*"},
+		},
+		{
+			Name:       "sre",
+			Users:      []string{"U000001"},
+			Groups:     []string{"@sre"},
+			Commands:   []string{"*"},
+			Clusters:   []string{"production"},
+			Namespaces: []string{"kube-system", "default"},
+		},
+	}
+	tt := map[string]struct {
+		text    string
+		user    synthetic.MockUser
+		replies []string
+	}{
+		"Allowed to everyone": {
+			text:    "list jobs",
+			user:    synthetic.NewMockUser("U000003", "@carol"),
+			replies: []string{"ran list jobs"},
+		},
+		"Command denied": {
+			text:    "build test-unit",
+			user:    synthetic.NewMockUser("U000003", "@carol"),
+			replies: []string{"Sorry @carol, you're not allowed to run `build`. Ask an admin if you need it"},
+		},
+		"Allowed by group": {
+			text:    "build test-unit",
+			user:    synthetic.NewMockUser("U000002", "@bob", "@developers"),
+			replies: []string{"ran build"},
+		},
+		"Job denied": {
+			text:    "build deploy-production",
+			user:    synthetic.NewMockUser("U000002", "@bob", "@developers"),
+			replies: []string{"Sorry @bob, you're not allowed to run `build` on job `deploy-production`"},
+		},
+		"Allowed by user ID": {
+			text:    "build deploy-production",
+			user:    synthetic.NewMockUser("U000001", "@alice"),
+			replies: []string{"ran build"},
+		},
+		"Allowed cluster and namespace": {
+			text:    "list pods production default",
+			user:    synthetic.NewMockUser("U000004", "@dave", "@sre"),
+			replies: []string{"ran list pods"},
+		},
+		"Missing cluster": {
+			text:    "list pods",
+			user:    synthetic.NewMockUser("U000004", "@dave", "@sre"),
+			replies: []string{"Sorry @dave, you're not allowed to run `list pods` without a `cluster`"},
+		},
+		"Allowed to the system user": {
+			text:    "build deploy-production",
+			user:    synthetic.NewMockUser("system", "@system"),
+			replies: []string{"ran build"},
+		},
+		"Namespace denied": {
+			text:    "list pods production payments",
+			user:    synthetic.NewMockUser("U000004", "@dave", "@sre"),
+			replies: []string{"Sorry @dave, you're not allowed to run `list pods` on namespace `payments`"},
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			h := NewHandler()
+			h.SetRouting(FirstMatch)
+			h.Use(Authorization(policies))
+			reply := func(c *Command) error {
+				c.Message().Reply("ran "+c.Name(), false)
+				return nil
+			}
+			specs := map[string]Spec{
+				"build":     {Verb: "build", Args: []Arg{{Name: "job", Required: true}}},
+				"list jobs": {Verb: "list", Subcommands: []string{"jobs"}},
+				"list pods": {
+					Verb:        "list",
+					Subcommands: []string{"pods"},
+					Args:        []Arg{{Name: "cluster"}, {Name: "namespace"}},
+				},
+			}
+			for name, spec := range specs {
+				if err := h.RegisterCommand(name, spec, reply); err != nil {
+					t.Fatal(err)
+				}
+			}
+			msg := synthetic.NewMockMessage(tc.text, true)
+			msg.SetUser(tc.user)
+
+			h.Dispatch(NewCommand(msg))
+
+			if len(msg.Replies()) != len(tc.replies) {
+				t.Fatalf("wrong replies %v should be %v", msg.Replies(), tc.replies)
+			}
+			for i, reply := range tc.replies {
+				if msg.Replies()[i] != reply {
+					t.Errorf("wrong reply `%s` should be `%s`", msg.Replies()[i], reply)
+				}
+			}
+			if len(h.Failures()) != 0 {
+				t.Errorf("denials counted as failures %v", h.Failures())
+			}
+		})
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	tt := map[string]struct {
+		policy Policy
+		valid  bool
+	}{
+		"Valid": {
+			policy: Policy{Name: "ok", Users: []string{"@alice"}, Commands: []string{"*"}},
+			valid:  true,
+		},
+		"No users": {
+			policy: Policy{Name: "nobody", Commands: []string{"*"}},
+			valid:  false,
+		},
+		"No commands": {
+			policy: Policy{Name: "nothing", Groups: []string{"@sre"}},
+			valid:  false,
+		},
+		"Wrong pattern": {
+			policy: Policy{Name: "wrong", Users: []string{"*"}, Commands: []string{"*"}, Jobs: []string{"deploy-["}},
+			valid:  false,
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			err := tc.policy.Validate()
+			if (err == nil) != tc.valid {
+				t.Errorf("wrong validation error %v for valid %v", err, tc.valid)
+			}
+		})
+	}
+}
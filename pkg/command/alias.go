@@ -0,0 +1,356 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ifosch/synthetic/pkg/jsonfile"
+	"github.com/ifosch/synthetic/pkg/tokenizer"
+)
+
+// aliasSpec is the Spec of the built-in alias command. Its arguments
+// are taken from the tokens, as the `key=value` parameters of the
+// expansion must be kept.
+var aliasSpec = Spec{
+	Verb: "alias",
+	Args: []Arg{
+		{Name: "name", Required: true},
+		{Name: "expansion", Required: true, Variadic: true},
+	},
+	AnyParams: true,
+	Flags:     []string{"global"},
+	Usage:     "alias <name> = <command>... [--global]",
+	Summary:   "Defines an alias for a command in this conversation, or everywhere with `--global`. `$1` to `$9` take the arguments of the alias, and `$*` all of them",
+	Examples: []string{
+		"alias deploy-api-staging = build deploy-service ENV=staging SERVICE=api",
+		"alias deploy = build deploy-service ENV=$1 SERVICE=$2 --global",
+	},
+	Category: CategoryChat,
+}
+
+// listAliasesSpec is the Spec of the built-in command listing the
+// aliases.
+var listAliasesSpec = Spec{
+	Verb:        "list",
+	Subcommands: []string{"aliases"},
+	Summary:     "Lists the aliases available in this conversation",
+	Category:    CategoryChat,
+}
+
+// deleteAliasSpec is the Spec of the built-in command deleting an
+// alias.
+var deleteAliasSpec = Spec{
+	Verb:        "delete",
+	Subcommands: []string{"alias"},
+	Args:        []Arg{{Name: "name", Required: true}},
+	Flags:       []string{"global"},
+	Summary:     "Deletes an alias of this conversation, or a global one with `--global`",
+	Examples:    []string{"delete alias deploy-api-staging", "delete alias deploy --global"},
+	Category:    CategoryChat,
+}
+
+// placeholder matches the positional placeholders in an expansion.
+var placeholder = regexp.MustCompile(`\$([1-9*])`)
+
+// Alias is a name standing for a command, with placeholders for its
+// arguments.
+type Alias struct {
+	Name      string `json:"name"`
+	Expansion string `json:"expansion"`
+	// ConversationID is the conversation where the alias is
+	// defined, or empty for the global ones.
+	ConversationID string    `json:"conversation_id,omitempty"`
+	Conversation   string    `json:"conversation,omitempty"`
+	Creator        string    `json:"creator"`
+	Created        time.Time `json:"created"`
+}
+
+// scope describes where the Alias is defined.
+func (a *Alias) scope() string {
+	if a.ConversationID == "" {
+		return "global"
+	}
+	return a.Conversation
+}
+
+// expand returns the tokens of the Alias expansion for the `args`
+// given to it. The arguments not taken by any placeholder are
+// appended.
+func (a *Alias) expand(args []string) ([]string, error) {
+	used := map[int]bool{}
+	expansion, err := tokenizer.Split(a.Expansion)
+	if err != nil {
+		return nil, fmt.Errorf("wrong expansion of the alias `%s`: %w", a.Name, err)
+	}
+	tokens := []string{}
+	for _, token := range expansion {
+		if token == "$*" {
+			for i := range args {
+				used[i] = true
+			}
+			tokens = append(tokens, args...)
+			continue
+		}
+		tokens = append(tokens, placeholder.ReplaceAllStringFunc(token, func(match string) string {
+			if match == "$*" {
+				for i := range args {
+					used[i] = true
+				}
+				return strings.Join(args, " ")
+			}
+			n, _ := strconv.Atoi(match[1:])
+			if n > len(args) {
+				err = fmt.Errorf("the alias `%s` needs at least %d arguments: `%s`", a.Name, n, a.Expansion)
+				return match
+			}
+			used[n-1] = true
+			return args[n-1]
+		}))
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, arg := range args {
+		if !used[i] {
+			tokens = append(tokens, arg)
+		}
+	}
+	return tokens, nil
+}
+
+// Aliases are the aliases defined by the users, saved to a file so
+// they survive restarts. It's safe for concurrent use.
+type Aliases struct {
+	sync.Mutex
+	filename string
+	aliases  []*Alias
+	now      func() time.Time
+}
+
+// NewAliases returns the Aliases saved in `filename`, if it exists.
+func NewAliases(filename string) (*Aliases, error) {
+	a := &Aliases{
+		filename: filename,
+		aliases:  []*Alias{},
+		now:      time.Now,
+	}
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &a.aliases); err != nil {
+		return nil, fmt.Errorf("error reading the aliases in %s: %w", filename, err)
+	}
+	return a, nil
+}
+
+// save writes the aliases to the file, replacing it atomically. It
+// must be called with the Aliases locked.
+func (a *Aliases) save() error {
+	return jsonfile.Save(a.filename, a.aliases)
+}
+
+// index returns the position of the alias `name` defined in
+// `conversationID`, or -1. It must be called with the Aliases locked.
+func (a *Aliases) index(name, conversationID string) int {
+	for i, alias := range a.aliases {
+		if alias.Name == name && alias.ConversationID == conversationID {
+			return i
+		}
+	}
+	return -1
+}
+
+// Lookup returns the alias `name` available in `conversationID`,
+// preferring the one defined in the conversation to the global one.
+func (a *Aliases) Lookup(name, conversationID string) *Alias {
+	a.Lock()
+	defer a.Unlock()
+	for _, scope := range []string{conversationID, ""} {
+		if i := a.index(name, scope); i >= 0 {
+			return a.aliases[i]
+		}
+	}
+	return nil
+}
+
+// Set defines `alias`, replacing the one with the same name and
+// scope, if any. It returns whether it replaced one.
+func (a *Aliases) Set(alias *Alias) (replaced bool, err error) {
+	a.Lock()
+	defer a.Unlock()
+	aliases := append([]*Alias{}, a.aliases...)
+	if i := a.index(alias.Name, alias.ConversationID); i >= 0 {
+		aliases[i] = alias
+		replaced = true
+	} else {
+		aliases = append(aliases, alias)
+	}
+	previous := a.aliases
+	a.aliases = aliases
+	if err := a.save(); err != nil {
+		a.aliases = previous
+		return false, fmt.Errorf("error saving the aliases: %w", err)
+	}
+	return replaced, nil
+}
+
+// Delete removes the alias `name` defined in `conversationID`, or the
+// global one if it's empty.
+func (a *Aliases) Delete(name, conversationID string) (*Alias, error) {
+	a.Lock()
+	defer a.Unlock()
+	i := a.index(name, conversationID)
+	if i < 0 {
+		return nil, fmt.Errorf("there's no alias `%s` here. Use `list aliases` to get the list of aliases", name)
+	}
+	alias := a.aliases[i]
+	previous := a.aliases
+	a.aliases = append(append([]*Alias{}, a.aliases[:i]...), a.aliases[i+1:]...)
+	if err := a.save(); err != nil {
+		a.aliases = previous
+		return nil, fmt.Errorf("error saving the aliases: %w", err)
+	}
+	return alias, nil
+}
+
+// List returns the aliases available in `conversationID`, sorted by
+// name.
+func (a *Aliases) List(conversationID string) []*Alias {
+	a.Lock()
+	defer a.Unlock()
+	result := []*Alias{}
+	for _, alias := range a.aliases {
+		if alias.ConversationID == "" || alias.ConversationID == conversationID {
+			result = append(result, alias)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// SetAliases expands the aliases in `aliases` before routing the
+// commands, and adds the built-in `alias`, `list aliases` and `delete
+// alias` commands to manage them.
+func (c *Handler) SetAliases(aliases *Aliases) {
+	c.aliases = aliases
+	c.commands = append(
+		c.commands,
+		&registration{name: "command.alias", spec: &aliasSpec, executor: c.alias},
+		&registration{name: "command.listAliases", spec: &listAliasesSpec, executor: c.listAliases},
+		&registration{name: "command.deleteAlias", spec: &deleteAliasSpec, executor: c.deleteAlias},
+	)
+}
+
+// expand replaces the tokens and the message text of `command` with
+// the expansion of the alias it starts with, if any, so the executors
+// parsing the text get the expansion too. Aliases aren't expanded
+// recursively.
+func (c *Handler) expand(command *Command) error {
+	tokens := command.Tokens()
+	if c.aliases == nil || len(tokens) == 0 {
+		return nil
+	}
+	alias := c.aliases.Lookup(tokens[0], command.Message().Conversation().ID())
+	if alias == nil {
+		return nil
+	}
+	expanded, err := alias.expand(tokens[1:])
+	if err != nil {
+		return err
+	}
+	command.tokenizedParams = expanded
+	command.message = &rewrittenMessage{Message: command.Message(), text: tokenizer.Join(expanded)}
+	return nil
+}
+
+// alias defines an alias from the tokens of `command`, like `alias
+// name = build job KEY=value`.
+func (c *Handler) alias(command *Command) error {
+	tokens := []string{}
+	for _, token := range command.Tokens()[1:] {
+		if token != "--global" {
+			tokens = append(tokens, token)
+		}
+	}
+	if len(tokens) < 3 || tokens[1] != "=" {
+		return &UsageError{&aliasSpec, "missing `=` after the alias name"}
+	}
+	name, expansion := tokens[0], tokens[2:]
+	if strings.ContainsAny(name, "=$\"'") || strings.HasPrefix(name, "-") {
+		return fmt.Errorf("`%s` is not a valid alias name", name)
+	}
+	if len(c.matching([]string{name}, nil)) > 0 {
+		return fmt.Errorf("`%s` is already a command, so it can't be an alias", name)
+	}
+	if len(c.matching(expansion, nil)) == 0 {
+		return c.unknown(expansion)
+	}
+
+	msg := command.Message()
+	alias := &Alias{
+		Name:      name,
+		Expansion: tokenizer.Join(expansion),
+		Creator:   msg.User().Name(),
+		Created:   c.aliases.now(),
+	}
+	if !command.Flag("global") {
+		alias.ConversationID = msg.Conversation().ID()
+		alias.Conversation = msg.Conversation().Name()
+	}
+	replaced, err := c.aliases.Set(alias)
+	if err != nil {
+		return err
+	}
+	verb := "Defined"
+	if replaced {
+		verb = "Redefined"
+	}
+	msg.Reply(fmt.Sprintf("%s the %s alias `%s` as `%s`", verb, alias.scope(), alias.Name, alias.Expansion), msg.Thread())
+	return nil
+}
+
+// listAliases replies with the aliases available in the conversation
+// of `command`.
+func (c *Handler) listAliases(command *Command) error {
+	msg := command.Message()
+	aliases := c.aliases.List(msg.Conversation().ID())
+	if len(aliases) == 0 {
+		msg.Reply("There are no aliases here", msg.Thread())
+		return nil
+	}
+	result := "The aliases available here are:\n"
+	for _, alias := range aliases {
+		result = fmt.Sprintf("%s- `%s`: `%s` (%s, by %s)\n", result, alias.Name, alias.Expansion, alias.scope(), alias.Creator)
+	}
+	msg.Reply(result, msg.Thread())
+	return nil
+}
+
+// deleteAlias deletes the alias named in `command`.
+func (c *Handler) deleteAlias(command *Command) error {
+	msg := command.Message()
+	conversationID := msg.Conversation().ID()
+	if command.Flag("global") {
+		conversationID = ""
+	}
+	alias, err := c.aliases.Delete(command.Arg("name"), conversationID)
+	if err != nil {
+		return err
+	}
+	msg.Reply(fmt.Sprintf("Deleted the %s alias `%s`", alias.scope(), alias.Name), msg.Thread())
+	return nil
+}
@@ -0,0 +1,117 @@
+package command
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+func TestAliases(t *testing.T) {
+	disableLogs()
+	general := synthetic.NewMockConversation("C000001", "#general")
+	random := synthetic.NewMockConversation("C000002", "#random")
+	type sent struct {
+		text         string
+		conversation synthetic.MockConversation
+		reply        string
+	}
+	tt := map[string][]sent{
+		"Alias with parameters": {
+			{"alias deploy-api-staging = build deploy-service ENV=staging SERVICE=api", general, "Defined the #general alias `deploy-api-staging` as `build deploy-service ENV=staging SERVICE=api`"},
+			{"deploy-api-staging BRANCH=main", general, "built deploy-service with BRANCH=main ENV=staging SERVICE=api"},
+		},
+		"Positional placeholders": {
+			{"alias deploy = build deploy-service ENV=$1 SERVICE=$2", general, "Defined the #general alias `deploy` as `build deploy-service ENV=$1 SERVICE=$2`"},
+			{"deploy production api", general, "built deploy-service with ENV=production SERVICE=api"},
+			{"deploy production", general, "the alias `deploy` needs at least 2 arguments: `build deploy-service ENV=$1 SERVICE=$2`"},
+		},
+		"All arguments": {
+			{"alias b = build $*", general, "Defined the #general alias `b` as `build $*`"},
+			{"b deploy-service ENV=staging", general, "built deploy-service with ENV=staging"},
+		},
+		"Per conversation": {
+			{"alias d = build deploy-service", general, "Defined the #general alias `d` as `build deploy-service`"},
+			{"d", random, "I don't know how to `d`. Use `help` to get the list of commands I know"},
+			{"list aliases", random, "There are no aliases here"},
+		},
+		"Global": {
+			{"alias d = build deploy-service --global", general, "Defined the global alias `d` as `build deploy-service`"},
+			{"alias d = build other-service", random, "Defined the #random alias `d` as `build other-service`"},
+			{"d", general, "built deploy-service with "},
+			{"d", random, "built other-service with "},
+			{"list aliases", random, "The aliases available here are:\n- `d`: `build deploy-service` (global, by @alice)\n- `d`: `build other-service` (#random, by @alice)\n"},
+		},
+		"Delete": {
+			{"alias d = build deploy-service --global", general, "Defined the global alias `d` as `build deploy-service`"},
+			{"delete alias d", general, "there's no alias `d` here. Use `list aliases` to get the list of aliases"},
+			{"delete alias d --global", random, "Deleted the global alias `d`"},
+			{"d", general, "I don't know how to `d`. Use `help` to get the list of commands I know"},
+		},
+		"Redefine": {
+			{"alias d = build deploy-service", general, "Defined the #general alias `d` as `build deploy-service`"},
+			{"alias d = build other-service", general, "Redefined the #general alias `d` as `build other-service`"},
+			{"d", general, "built other-service with "},
+		},
+		"Wrong aliases": {
+			{"alias build = build deploy-service", general, "`build` is already a command, so it can't be an alias"},
+			{"alias d build deploy-service", general, "missing `=` after the alias name. Usage: `alias <name> = <command>... [--global]`"},
+			{"alias d = launch deploy-service", general, "I don't know how to `launch`. Use `help` to get the list of commands I know"},
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "aliases.json")
+			aliases, err := NewAliases(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := NewHandler()
+			h.SetRouting(FirstMatch)
+			h.SetAliases(aliases)
+			err = h.RegisterCommand(
+				"build",
+				Spec{Verb: "build", Args: []Arg{{Name: "job", Required: true}}, AnyParams: true},
+				func(c *Command) error {
+					params := []string{}
+					for key, value := range c.Params() {
+						params = append(params, key+"="+value)
+					}
+					sort.Strings(params)
+					if !strings.HasPrefix(c.Message().Text(), "build ") {
+						return fmt.Errorf("wrong text `%s`", c.Message().Text())
+					}
+					c.Message().Reply("built "+c.Arg("job")+" with "+strings.Join(params, " "), false)
+					return nil
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, s := range tc {
+				msg := synthetic.NewMockMessage(s.text, true)
+				msg.SetUser(synthetic.NewMockUser("U000001", "@alice"))
+				msg.SetConversation(s.conversation)
+
+				h.Dispatch(NewCommand(msg))
+
+				if len(msg.Replies()) != 1 || msg.Replies()[0] != s.reply {
+					t.Errorf("wrong replies %v to message %d should be `%s`", msg.Replies(), i, s.reply)
+				}
+			}
+
+			// The aliases survive restarts
+			loaded, err := NewAliases(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(loaded.aliases) != len(aliases.aliases) {
+				t.Errorf("wrong aliases %v loaded should be %v", loaded.aliases, aliases.aliases)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcome is how a Command ended, as recorded in the audit log.
+type Outcome string
+
+// Outcomes of the commands in the audit log.
+const (
+	Succeeded Outcome = "succeeded"
+	Failed    Outcome = "failed"
+	Panicked  Outcome = "panicked"
+	Denied    Outcome = "denied"
+	Throttled Outcome = "throttled"
+	Cancelled Outcome = "cancelled"
+)
+
+// defaultAuditLimit is the number of entries the `audit` command
+// replies with, unless told otherwise.
+const defaultAuditLimit = 20
+
+// auditSpec is the Spec of the built-in audit command.
+var auditSpec = Spec{
+	Verb:     "audit",
+	Params:   []string{"user", "command", "since", "until", "limit"},
+	Summary:  "Lists the last commands I ran. `since` and `until` take dates, times or durations",
+	Examples: []string{"audit user=@alice", "audit command=build since=24h", "audit since=2021-10-01 until=2021-10-02 limit=50"},
+	Category: CategoryChat,
+}
+
+// AuditEntry is the record of a Command in the audit log.
+type AuditEntry struct {
+	Time           time.Time         `json:"time"`
+	ID             int               `json:"id"`
+	User           string            `json:"user"`
+	UserID         string            `json:"user_id"`
+	Conversation   string            `json:"conversation"`
+	ConversationID string            `json:"conversation_id"`
+	Text           string            `json:"text"`
+	Command        string            `json:"command"`
+	Executor       string            `json:"executor"`
+	Args           map[string]string `json:"args,omitempty"`
+	Params         map[string]string `json:"params,omitempty"`
+	Flags          map[string]bool   `json:"flags,omitempty"`
+	Outcome        Outcome           `json:"outcome"`
+	Error          string            `json:"error,omitempty"`
+	DurationMS     int64             `json:"duration_ms"`
+}
+
+// AuditFilter selects entries from the audit log. Its zero value
+// selects all of them.
+type AuditFilter struct {
+	// User matches the user name or ID.
+	User string
+	// Command matches the verb and subcommands, like `list pods`.
+	Command string
+	Since   time.Time
+	Until   time.Time
+}
+
+// matches reports whether `entry` passes the filter.
+func (f *AuditFilter) matches(entry *AuditEntry) bool {
+	switch {
+	case f.User != "" && f.User != entry.User && f.User != entry.UserID:
+		return false
+	case f.Command != "" && f.Command != entry.Command:
+		return false
+	case !f.Since.IsZero() && entry.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && entry.Time.After(f.Until):
+		return false
+	}
+	return true
+}
+
+// AuditLog is an append-only JSON Lines file recording the commands
+// run. It's safe for concurrent use.
+type AuditLog struct {
+	sync.Mutex
+	filename string
+	file     *os.File
+}
+
+// NewAuditLog opens the audit log in `filename`, creating it if it
+// doesn't exist.
+func NewAuditLog(filename string) (*AuditLog, error) {
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLog{filename: filename, file: file}, nil
+}
+
+// Record appends `entry` to the audit log.
+func (a *AuditLog) Record(entry *AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	a.Lock()
+	defer a.Unlock()
+	_, err = a.file.Write(append(line, '\n'))
+	return err
+}
+
+// Query returns the last `limit` entries of the audit log passing
+// `filter`, oldest first. Zero means no limit.
+func (a *AuditLog) Query(filter AuditFilter, limit int) ([]*AuditEntry, error) {
+	file, err := os.Open(a.filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := []*AuditEntry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		entry := &AuditEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			return nil, fmt.Errorf("error reading the audit log: %w", err)
+		}
+		if !filter.matches(entry) {
+			continue
+		}
+		entries = append(entries, entry)
+		if limit > 0 && len(entries) > limit {
+			entries = entries[1:]
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// Close closes the audit log.
+func (a *AuditLog) Close() error {
+	return a.file.Close()
+}
+
+// SetAuditLog records every command routed by the Handler in
+// `auditLog`, and adds the built-in `audit` command to query it. Only
+// the `admins`, users or user groups as in a Policy, can run it.
+func (c *Handler) SetAuditLog(auditLog *AuditLog, admins []string) {
+	c.auditLog = auditLog
+	c.auditors = &Policy{Name: "admins", Users: admins, Groups: admins}
+	c.commands = append(c.commands, &registration{name: "command.audit", spec: &auditSpec, executor: c.audit})
+}
+
+// record adds `command` to the audit log, if any, with the outcome
+// of `err`.
+func (c *Handler) record(command *Command, started time.Time, err error) {
+	if c.auditLog == nil {
+		return
+	}
+	msg := command.Message()
+	entry := &AuditEntry{
+		Time:           started,
+		ID:             command.ID(),
+		User:           msg.User().Name(),
+		UserID:         msg.User().ID(),
+		Conversation:   msg.Conversation().Name(),
+		ConversationID: msg.Conversation().ID(),
+		Text:           msg.Text(),
+		Command:        strings.Join(command.Spec().Words(), " "),
+		Executor:       command.Name(),
+		Outcome:        outcome(err),
+		DurationMS:     time.Since(started).Milliseconds(),
+	}
+	if command.arguments != nil {
+		entry.Args = command.arguments.Args
+		entry.Params = command.arguments.Params
+		entry.Flags = command.arguments.Flags
+	}
+	if params := command.trackedParams(); params != nil {
+		entry.Params = params
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if err := c.auditLog.Record(entry); err != nil {
+		c.report(command, fmt.Errorf("error writing the audit log: %w", err))
+	}
+}
+
+// outcome returns the outcome of a command returning `err`.
+func outcome(err error) Outcome {
+	var deniedErr *DeniedError
+	var throttledErr *ThrottledError
+	var panicErr *PanicError
+	switch {
+	case err == nil:
+		return Succeeded
+	case errors.As(err, &deniedErr):
+		return Denied
+	case errors.As(err, &throttledErr):
+		return Throttled
+	case errors.As(err, &panicErr):
+		return Panicked
+	case errors.Is(err, ErrSessionCancelled), errors.Is(err, context.Canceled):
+		return Cancelled
+	}
+	return Failed
+}
+
+// audit replies with the entries in the audit log matching the
+// parameters of `command`.
+func (c *Handler) audit(command *Command) error {
+	msg := command.Message()
+	if !c.auditors.appliesTo(msg.User()) {
+		return &DeniedError{User: msg.User().Name(), Command: "audit", Reason: "as it's only for admins"}
+	}
+
+	params := command.Params()
+	filter := AuditFilter{User: params["user"], Command: params["command"]}
+	var err error
+	if filter.Since, err = parseTime(params["since"]); err != nil {
+		return err
+	}
+	if filter.Until, err = parseTime(params["until"]); err != nil {
+		return err
+	}
+	limit := defaultAuditLimit
+	if params["limit"] != "" {
+		if limit, err = strconv.Atoi(params["limit"]); err != nil || limit <= 0 {
+			return fmt.Errorf("`%s` is not a valid limit", params["limit"])
+		}
+	}
+
+	entries, err := c.auditLog.Query(filter, limit)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		msg.Reply("I didn't find any command", msg.Thread())
+		return nil
+	}
+	result := ""
+	for _, entry := range entries {
+		result = fmt.Sprintf(
+			"%s- %s `#%d` `%s` by %s in %s: %s after %v\n",
+			result,
+			entry.Time.Format("2006-01-02 15:04:05"),
+			entry.ID,
+			entry.Text,
+			entry.User,
+			entry.Conversation,
+			entry.Outcome,
+			time.Duration(entry.DurationMS)*time.Millisecond,
+		)
+	}
+	msg.Reply(result, msg.Thread())
+	return nil
+}
+
+// parseTime parses `value` as a date, a date and time, or a duration
+// back from now. Empty values are the zero time.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("`%s` is not a valid time. Use dates like `2021-10-01`, times like `2021-10-01T09:30` or durations like `24h`", value)
+}
@@ -0,0 +1,191 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+// auditedHandler returns a Handler recording the commands in an audit
+// log in a temporary directory, with `@admin` as its admin.
+func auditedHandler(t *testing.T) (*Handler, *AuditLog) {
+	auditLog, err := NewAuditLog(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { auditLog.Close() })
+	h := NewHandler()
+	h.SetAuditLog(auditLog, []string{"@admin"})
+	err = h.RegisterCommand(
+		"build",
+		Spec{Verb: "build", Args: []Arg{{Name: "job", Required: true}}, AnyParams: true},
+		func(c *Command) error {
+			if c.Arg("job") == "broken" {
+				return fmt.Errorf("the job `broken` failed")
+			}
+			// The job asks for the missing parameters.
+			params := map[string]string{"INDEX": "users"}
+			for name, value := range c.Params() {
+				params[name] = value
+			}
+			c.TrackParams(params)
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h, auditLog
+}
+
+func TestAuditLog(t *testing.T) {
+	disableLogs()
+	h, auditLog := auditedHandler(t)
+	alice := synthetic.NewMockUser("U000001", "@alice")
+	bob := synthetic.NewMockUser("U000002", "@bob")
+	for _, sent := range []struct {
+		text string
+		user synthetic.MockUser
+	}{
+		{"build deploy ENV=production", alice},
+		{"build broken", bob},
+		{"hello", bob},
+	} {
+		msg := synthetic.NewMockMessage(sent.text, true)
+		msg.SetUser(sent.user)
+		msg.SetConversation(synthetic.NewMockConversation("CH00001", "#general"))
+		h.Dispatch(NewCommand(msg))
+	}
+
+	entries, err := auditLog.Query(AuditFilter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*AuditEntry{
+		{
+			ID:             1,
+			User:           "@alice",
+			UserID:         "U000001",
+			Conversation:   "#general",
+			ConversationID: "CH00001",
+			Text:           "build deploy ENV=production",
+			Command:        "build",
+			Executor:       "build",
+			Args:           map[string]string{"job": "deploy"},
+			Params:         map[string]string{"ENV": "production", "INDEX": "users"},
+			Outcome:        Succeeded,
+		},
+		{
+			ID:             2,
+			User:           "@bob",
+			UserID:         "U000002",
+			Conversation:   "#general",
+			ConversationID: "CH00001",
+			Text:           "build broken",
+			Command:        "build",
+			Executor:       "build",
+			Args:           map[string]string{"job": "broken"},
+			Outcome:        Failed,
+			Error:          "the job `broken` failed",
+		},
+	}
+	for _, entry := range entries {
+		if time.Since(entry.Time) > time.Minute {
+			t.Errorf("wrong time %v in entry %d", entry.Time, entry.ID)
+		}
+		entry.Time = time.Time{}
+		entry.DurationMS = 0
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("wrong entries %s should be %s", toJSON(entries), toJSON(expected))
+	}
+}
+
+// toJSON returns `value` in JSON, to show it in the test errors.
+func toJSON(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+func TestAuditCommand(t *testing.T) {
+	disableLogs()
+	tt := map[string]struct {
+		text    string
+		user    synthetic.MockUser
+		replies []string
+	}{
+		"By user": {
+			text:    "audit user=@alice",
+			user:    synthetic.NewMockUser("U000003", "@admin"),
+			replies: []string{"`#1` `build deploy` by @alice in : succeeded after"},
+		},
+		"By command and time": {
+			text: "audit command=build since=1h",
+			user: synthetic.NewMockUser("U000003", "@admin"),
+			replies: []string{
+				"`#1` `build deploy` by @alice in : succeeded after",
+				"`#2` `build broken` by @bob in : failed after",
+			},
+		},
+		"Limited": {
+			text:    "audit limit=1",
+			user:    synthetic.NewMockUser("U000003", "@admin"),
+			replies: []string{"`#2` `build broken` by @bob in : failed after"},
+		},
+		"Nothing found": {
+			text:    "audit since=2000-01-01 until=2000-01-02",
+			user:    synthetic.NewMockUser("U000003", "@admin"),
+			replies: []string{"I didn't find any command"},
+		},
+		"Wrong time": {
+			text:    "audit since=yesterday",
+			user:    synthetic.NewMockUser("U000003", "@admin"),
+			replies: []string{"`yesterday` is not a valid time"},
+		},
+		"Not an admin": {
+			text:    "audit",
+			user:    synthetic.NewMockUser("U000001", "@alice"),
+			replies: []string{"Sorry @alice, you're not allowed to run `audit` as it's only for admins"},
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			h, _ := auditedHandler(t)
+			h.SetRouting(FirstMatch)
+			for _, sent := range []struct {
+				text string
+				user synthetic.MockUser
+			}{
+				{"build deploy", synthetic.NewMockUser("U000001", "@alice")},
+				{"build broken", synthetic.NewMockUser("U000002", "@bob")},
+			} {
+				msg := synthetic.NewMockMessage(sent.text, true)
+				msg.SetUser(sent.user)
+				h.Dispatch(NewCommand(msg))
+			}
+			msg := synthetic.NewMockMessage(tc.text, true)
+			msg.SetUser(tc.user)
+
+			h.Dispatch(NewCommand(msg))
+
+			if len(msg.Replies()) != 1 {
+				t.Fatalf("wrong replies %v", msg.Replies())
+			}
+			reply := msg.Replies()[0]
+			if lines := strings.Count(reply, "\n"); lines > 0 && lines != len(tc.replies) {
+				t.Errorf("wrong number of entries in `%s` should be %v", reply, len(tc.replies))
+			}
+			for _, expected := range tc.replies {
+				if !strings.Contains(reply, expected) {
+					t.Errorf("`%s` not found in `%s`", expected, reply)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ifosch/synthetic/pkg/tokenizer"
+)
+
+// Operators chaining commands in a message, like in a shell: the
+// command after `&&` only runs if the previous one succeeded, the one
+// after `||` only if it failed, and the one after `;` always runs.
+const (
+	and      = "&&"
+	or       = "||"
+	sequence = ";"
+)
+
+// step is a command in a chain, with the operator before it, empty
+// for the first one.
+type step struct {
+	operator string
+	tokens   []string
+}
+
+// text returns the text of the step command.
+func (s *step) text() string {
+	return tokenizer.Join(s.tokens)
+}
+
+// splitChain splits `tokens` at the chain operators not quoted. A `;`
+// can also be at the end of the chain.
+func splitChain(tokens []tokenizer.Token) ([]*step, error) {
+	steps := []*step{{}}
+	last := steps[0]
+	for _, token := range tokens {
+		operator := token.Value
+		if token.Quoted || (operator != and && operator != or && operator != sequence) {
+			last.tokens = append(last.tokens, token.Value)
+			continue
+		}
+		if len(last.tokens) == 0 {
+			return nil, fmt.Errorf("there's no command before `%s`", operator)
+		}
+		last = &step{operator: operator}
+		steps = append(steps, last)
+	}
+	if len(last.tokens) == 0 && len(steps) > 1 {
+		if last.operator != sequence {
+			return nil, fmt.Errorf("there's no command after `%s`", last.operator)
+		}
+		steps = steps[:len(steps)-1]
+	}
+	return steps, nil
+}
+
+// chain returns the steps chained in `command`, or a single step
+// with all its tokens when its first command is Unchained.
+func (c *Handler) chain(command *Command) ([]*step, error) {
+	first := []string{}
+	for _, token := range command.tokens {
+		if !token.Quoted && (token.Value == and || token.Value == or || token.Value == sequence) {
+			break
+		}
+		first = append(first, token.Value)
+	}
+	if len(first) < len(command.tokens) {
+		for _, r := range c.matching(first, nil) {
+			if r.spec.Unchained {
+				return []*step{{tokens: command.Tokens()}}, nil
+			}
+		}
+	}
+	return splitChain(command.tokens)
+}
+
+// runChain runs the `steps` chained in `command` one after another,
+// as if each of them was sent in its own message, replying with the
+// progress and a summary of the outcomes in the end.
+func (c *Handler) runChain(command *Command, steps []*step) {
+	msg := command.Message()
+	summary := []string{}
+	var err error
+	for i, s := range steps {
+		skip := (s.operator == and && err != nil) || (s.operator == or && err == nil)
+		select {
+		case <-c.stopping:
+			skip = true
+		default:
+		}
+		if skip {
+			summary = append(summary, fmt.Sprintf("- `%s`: skipped", s.text()))
+			continue
+		}
+
+		msg.Reply(fmt.Sprintf("Step %d of %d: `%s`", i+1, len(steps), s.text()), msg.Thread())
+		stepCommand := NewCommand(&rewrittenMessage{Message: msg, text: s.text()})
+		stepCommand.ctx = command.ctx
+		err = c.runRouted(stepCommand)
+		summary = append(summary, fmt.Sprintf("- `%s`: %s", s.text(), outcome(err)))
+	}
+	msg.Reply(fmt.Sprintf("Finished `%s`:\n%s", msg.Text(), strings.Join(summary, "\n")), msg.Thread())
+}
@@ -0,0 +1,185 @@
+package command
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+	"github.com/ifosch/synthetic/pkg/tokenizer"
+)
+
+func TestSplitChain(t *testing.T) {
+	tt := map[string]struct {
+		input string
+		steps []step
+		err   string
+	}{
+		"Single command": {
+			input: "build deploy ENV=staging",
+			steps: []step{{"", []string{"build", "deploy", "ENV=staging"}}},
+		},
+		"All the operators": {
+			input: "build a && build b || build c ; build d",
+			steps: []step{
+				{"", []string{"build", "a"}},
+				{"&&", []string{"build", "b"}},
+				{"||", []string{"build", "c"}},
+				{";", []string{"build", "d"}},
+			},
+		},
+		"Attached semicolon": {
+			input: "build a; build b;",
+			steps: []step{
+				{"", []string{"build", "a"}},
+				{";", []string{"build", "b"}},
+			},
+		},
+		"Quoted operators": {
+			input: "build a MSG=\"x && y\" \\; '||'",
+			steps: []step{{"", []string{"build", "a", "MSG=x && y", ";", "||"}}},
+		},
+		"Missing command before": {
+			input: "&& build a",
+			err:   "there's no command before `&&`",
+		},
+		"Missing command between": {
+			input: "build a && || build b",
+			err:   "there's no command before `||`",
+		},
+		"Missing command after": {
+			input: "build a &&",
+			err:   "there's no command after `&&`",
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			tokens, err := tokenizer.Scan(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			steps, err := splitChain(tokens)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("wrong error %v should be `%s`", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := []step{}
+			for _, s := range steps {
+				result = append(result, *s)
+			}
+			if !reflect.DeepEqual(result, tc.steps) {
+				t.Errorf("wrong steps %v should be %v", result, tc.steps)
+			}
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	disableLogs()
+	tt := map[string]struct {
+		input   string
+		replies []string
+	}{
+		"And": {
+			input: "build a && build b",
+			replies: []string{
+				"Step 1 of 2: `build a`",
+				"built a",
+				"Step 2 of 2: `build b`",
+				"built b",
+				"Finished `build a && build b`:\n- `build a`: succeeded\n- `build b`: succeeded",
+			},
+		},
+		"And after a failure": {
+			input: "build broken && build b",
+			replies: []string{
+				"Step 1 of 2: `build broken`",
+				"the job `broken` failed",
+				"Finished `build broken && build b`:\n- `build broken`: failed\n- `build b`: skipped",
+			},
+		},
+		"Or": {
+			input: "build broken || build a; build b || build c",
+			replies: []string{
+				"Step 1 of 4: `build broken`",
+				"the job `broken` failed",
+				"Step 2 of 4: `build a`",
+				"built a",
+				"Step 3 of 4: `build b`",
+				"built b",
+				"Finished `build broken || build a; build b || build c`:\n- `build broken`: failed\n- `build a`: succeeded\n- `build b`: succeeded\n- `build c`: skipped",
+			},
+		},
+		"Sequence after a failure": {
+			input: "launch a ; build b",
+			replies: []string{
+				"Step 1 of 2: `launch a`",
+				"I don't know how to `launch`. Use `help` to get the list of commands I know",
+				"Step 2 of 2: `build b`",
+				"built b",
+				"Finished `launch a ; build b`:\n- `launch a`: failed\n- `build b`: succeeded",
+			},
+		},
+		"Wrong chain": {
+			input:   "build a ||",
+			replies: []string{"there's no command after `||`"},
+		},
+		"Unchained": {
+			input:   "later build a && build \"b && c\"; build d",
+			replies: []string{"later `build a && build \"b && c\" ; build d`"},
+		},
+		"Unchained in a chain": {
+			input: "build a && later build b || build c",
+			replies: []string{
+				"Step 1 of 3: `build a`",
+				"built a",
+				"Step 2 of 3: `later build b`",
+				"later `build b`",
+				"Finished `build a && later build b || build c`:\n- `build a`: succeeded\n- `later build b`: succeeded\n- `build c`: skipped",
+			},
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			h := NewHandler()
+			h.SetRouting(FirstMatch)
+			err := h.RegisterCommand(
+				"build",
+				Spec{Verb: "build", Args: []Arg{{Name: "job", Required: true}}},
+				func(c *Command) error {
+					if c.Arg("job") == "broken" {
+						return fmt.Errorf("the job `broken` failed")
+					}
+					c.Message().Reply("built "+c.Arg("job"), false)
+					return nil
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = h.RegisterCommand(
+				"later",
+				Spec{Verb: "later", Args: []Arg{{Name: "command", Required: true, Variadic: true}}, AnyParams: true, Unchained: true},
+				func(c *Command) error {
+					c.Message().Reply(fmt.Sprintf("later `%s`", c.Rest(1)), false)
+					return nil
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg := synthetic.NewMockMessage(tc.input, true)
+
+			h.Dispatch(NewCommand(msg))
+
+			if !reflect.DeepEqual(msg.Replies(), tc.replies) {
+				t.Errorf("wrong replies %q should be %q", msg.Replies(), tc.replies)
+			}
+		})
+	}
+}
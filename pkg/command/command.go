@@ -1,25 +1,80 @@
 package command
 
 import (
+	"context"
 	"strings"
-	"unicode"
+	"sync"
+	"time"
 
 	"github.com/ifosch/synthetic/pkg/synthetic"
+	"github.com/ifosch/synthetic/pkg/tokenizer"
 )
 
 // Command represents an instance of a command from a message, ready
 // to be executed
 type Command struct {
 	tokenizedParams []string
+	tokens          []tokenizer.Token
+	tokenizeErr     error
 	message         synthetic.Message
+	name            string
+	spec            *Spec
+	arguments       *Arguments
+	ctx             context.Context
+	status          *status
+	session         *Session
 }
 
-// NewCommand creates a new instance of Command based on a message
+// State is the state of a Command in the Handler.
+type State string
+
+const (
+	// Queued commands wait for a concurrency slot to run.
+	Queued State = "queued"
+	// Running commands are being run by their Executor.
+	Running State = "running"
+	// Confirming commands wait for their users to confirm them.
+	Confirming State = "waiting for confirmation"
+	// Waiting commands wait for their users to answer a question.
+	Waiting State = "waiting for an answer"
+	// Cancelling commands had their context cancelled, but their
+	// Executor didn't return yet.
+	Cancelling State = "cancelling"
+)
+
+// status holds the progress of a Command, shared by all its copies.
+type status struct {
+	sync.Mutex
+	id      int
+	state   State
+	started time.Time
+	url     string
+	params  map[string]string
+	cancel  context.CancelFunc
+	// release gives back the Handler slot held by the Command, if
+	// any.
+	release func()
+}
+
+// NewCommand creates a new instance of Command based on a message.
+// When the text of the message can't be tokenized, like with an
+// unbalanced quote, its tokens are just the words in it, and the error
+// is replied if it's sent to the bot.
 func NewCommand(message synthetic.Message) *Command {
-	return &Command{
-		message:         message,
-		tokenizedParams: tokenizeCommand(message.Text()),
+	command := &Command{
+		message: message,
+		status:  &status{},
+	}
+	command.tokens, command.tokenizeErr = tokenizer.Scan(message.Text())
+	if command.tokenizeErr != nil {
+		command.tokenizedParams = strings.Fields(message.Text())
+		return command
+	}
+	command.tokenizedParams = []string{}
+	for _, token := range command.tokens {
+		command.tokenizedParams = append(command.tokenizedParams, token.Value)
 	}
+	return command
 }
 
 // Message returns the message from the command
@@ -27,30 +82,170 @@ func (c *Command) Message() synthetic.Message {
 	return c.message
 }
 
-// Parses the message and returns a list of tokens for the command
-// logic to act on it.
-func tokenizeCommand(input string) []string {
-	// Solution taken from
-	// https://groups.google.com/g/golang-nuts/c/pNwqLyfl2co/m/APaZSSvQUAAJ
-	lastQuote := rune(0)
-	// Must return true for symbols that delimit a field
-	f := func(c rune) bool {
-		switch {
-		case c == lastQuote:
-			// when the quotation ends
-			lastQuote = rune(0)
-			return false
-		case lastQuote != rune(0):
-			//when we are inside a quotation
-			return false
-		case unicode.In(c, unicode.Quotation_Mark):
-			// when c is a valid quotation mark symbol starts a quotation
-			lastQuote = c
-			return false
-		default:
-			return unicode.IsSpace(c)
-
-		}
-	}
-	return strings.FieldsFunc(input, f)
+// Name returns the name the executor running the command was
+// registered with.
+func (c *Command) Name() string {
+	return c.name
+}
+
+// Spec returns the Spec of the command the Command was routed to, or
+// nil for the passive listeners.
+func (c *Command) Spec() *Spec {
+	return c.spec
+}
+
+// Session returns the Session with the user who sent the Command, or
+// nil for the passive listeners.
+func (c *Command) Session() *Session {
+	return c.session
+}
+
+// Tokens returns the tokens in the message text.
+func (c *Command) Tokens() []string {
+	return c.tokenizedParams
+}
+
+// Rest returns the text of the tokens from the `from` one on, like
+// the command given to `schedule`. Its chain operators not quoted,
+// like `&&`, are kept as such.
+func (c *Command) Rest(from int) string {
+	if c.tokenizeErr != nil || from >= len(c.tokens) {
+		return strings.Join(c.tokenizedParams[min(from, len(c.tokenizedParams)):], " ")
+	}
+	return tokenizer.JoinTokens(c.tokens[from:])
+}
+
+// Arg returns the value of the positional argument `name`, or an
+// empty string if it wasn't provided.
+func (c *Command) Arg(name string) string {
+	if c.arguments == nil {
+		return ""
+	}
+	return c.arguments.Args[name]
+}
+
+// Params returns the `key=value` parameters of the command.
+func (c *Command) Params() map[string]string {
+	if c.arguments == nil {
+		return map[string]string{}
+	}
+	return c.arguments.Params
+}
+
+// Flag reports whether the `--name` flag was provided.
+func (c *Command) Flag(name string) bool {
+	if c.arguments == nil {
+		return false
+	}
+	return c.arguments.Flags[name]
+}
+
+// Context returns the context of the command. Executors must pass it
+// to any long running or blocking call, so they stop when the command
+// times out or is cancelled.
+func (c *Command) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// WithContext returns a copy of the command using `ctx` as its
+// context.
+func (c *Command) WithContext(ctx context.Context) *Command {
+	command := *c
+	command.ctx = ctx
+	return &command
+}
+
+// Track records `url` as the place where users can follow the
+// progress of the command, like a Jenkins build page. It's given to
+// the users when the bot can't complete the command.
+func (c *Command) Track(url string) {
+	c.status.Lock()
+	defer c.status.Unlock()
+	c.status.url = url
+}
+
+// TrackingURL returns the URL recorded with Track, if any.
+func (c *Command) TrackingURL() string {
+	c.status.Lock()
+	defer c.status.Unlock()
+	return c.status.url
+}
+
+// TrackParams records `params` as the parameters the command runs
+// with, like when it asked its user for the missing ones, so they're
+// audited instead of the ones in the message.
+func (c *Command) TrackParams(params map[string]string) {
+	c.status.Lock()
+	defer c.status.Unlock()
+	c.status.params = map[string]string{}
+	for name, value := range params {
+		c.status.params[name] = value
+	}
+}
+
+// trackedParams returns the parameters recorded with TrackParams, if
+// any.
+func (c *Command) trackedParams() map[string]string {
+	c.status.Lock()
+	defer c.status.Unlock()
+	return c.status.params
+}
+
+// ID returns the identifier assigned to the command when it was
+// dispatched, or zero if it wasn't.
+func (c *Command) ID() int {
+	c.status.Lock()
+	defer c.status.Unlock()
+	return c.status.id
+}
+
+// State returns the state of the command.
+func (c *Command) State() State {
+	c.status.Lock()
+	defer c.status.Unlock()
+	return c.status.state
+}
+
+// Started returns when the command was dispatched.
+func (c *Command) Started() time.Time {
+	c.status.Lock()
+	defer c.status.Unlock()
+	return c.status.started
+}
+
+func (c *Command) setState(state State) {
+	c.status.Lock()
+	defer c.status.Unlock()
+	c.status.state = state
+}
+
+// rewrittenMessage is a message with its text replaced, like once its
+// alias is expanded.
+type rewrittenMessage struct {
+	synthetic.Message
+	text string
+}
+
+// Text returns the replaced text.
+func (m *rewrittenMessage) Text() string {
+	return m.text
+}
+
+// WithOverflow keeps the replaced text in the message with `overflow`.
+func (m *rewrittenMessage) WithOverflow(overflow synthetic.Overflow) synthetic.Message {
+	return &rewrittenMessage{Message: m.Message.WithOverflow(overflow), text: m.text}
+}
+
+// bind returns a copy of the command to be run by the executor
+// registered as `name` with `spec`, with the arguments parsed for it.
+func (c *Command) bind(name string, spec *Spec, arguments *Arguments) *Command {
+	bound := *c
+	bound.name = name
+	bound.spec = spec
+	bound.arguments = arguments
+	bound.status = &status{}
+	return &bound
 }
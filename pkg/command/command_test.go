@@ -1,11 +1,16 @@
 package command
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
 
 func TestTokenizeCommand(t *testing.T) {
 	tt := []struct {
 		input  string
 		result []string
+		err    bool
 	}{
 		{
 			input:  "",
@@ -21,16 +26,25 @@ func TestTokenizeCommand(t *testing.T) {
 		},
 		{
 			input:  "build  deploy      INDEX=\"users\"",
-			result: []string{"build", "deploy", "INDEX=\"users\""},
+			result: []string{"build", "deploy", "INDEX=users"},
 		},
 		{
 			input:  "build  deploy      INDEX=\"users ducks\"",
-			result: []string{"build", "deploy", "INDEX=\"users ducks\""},
+			result: []string{"build", "deploy", "INDEX=users ducks"},
+		},
+		{
+			input:  "build  deploy      INDEX=\"users ducks",
+			result: []string{"build", "deploy", "INDEX=\"users", "ducks"},
+			err:    true,
 		},
 	}
 	for _, tc := range tt {
 		t.Run(tc.input, func(t *testing.T) {
-			result := tokenizeCommand(tc.input)
+			command := NewCommand(synthetic.NewMockMessage(tc.input, true))
+			result := command.Tokens()
+			if (command.tokenizeErr != nil) != tc.err {
+				t.Errorf("unexpected tokenizing error %v", command.tokenizeErr)
+			}
 			if len(result) != len(tc.result) {
 				t.Errorf("expected %d results but got %d", len(tc.result), len(result))
 			}
@@ -0,0 +1,147 @@
+package command
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+)
+
+// queuedReaction is the reaction added to the messages waiting for a
+// concurrency slot.
+const queuedReaction = "hourglass_flowing_sand"
+
+// SetConcurrency limits the number of commands, including the passive
+// listeners, run at the same time. Commands waiting for their own
+// MaxConcurrent limit, or for their users to answer, don't count.
+// Zero means no limit. It must be called before EventLoop starts.
+func (c *Handler) SetConcurrency(max int) {
+	c.slots = newSlots(max)
+}
+
+// QueueDepth returns the number of commands waiting for a concurrency
+// slot.
+func (c *Handler) QueueDepth() int {
+	return int(atomic.LoadInt32(&c.queued))
+}
+
+// newSlots returns a semaphore allowing `max` holders, or nil when
+// `max` is zero.
+func newSlots(max int) chan struct{} {
+	if max <= 0 {
+		return nil
+	}
+	return make(chan struct{}, max)
+}
+
+// acquire takes a slot from `slots` for `command`, waiting for one
+// to be released if all of them are taken, unless `ctx` is done
+// before. It returns the function releasing the slot.
+func (c *Handler) acquire(ctx context.Context, slots chan struct{}, command *Command, what string) (release func(), err error) {
+	if slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case slots <- struct{}{}:
+	default:
+		depth := atomic.AddInt32(&c.queued, 1)
+		log.Printf("Concurrency limit of %v reached for %v, %v waiting in queue", cap(slots), what, depth)
+		command.Message().React(queuedReaction)
+		defer command.Message().Unreact(queuedReaction)
+		defer atomic.AddInt32(&c.queued, -1)
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return func() { <-slots }, nil
+}
+
+// hold takes one of the Handler slots for `command`, waiting for it
+// unless the command is cancelled before. It's given back with
+// unhold.
+func (c *Handler) hold(command *Command) error {
+	release, err := c.acquire(command.Context(), c.slots, command, "all commands")
+	if err != nil {
+		return err
+	}
+	command.status.Lock()
+	defer command.status.Unlock()
+	command.status.release = release
+	return nil
+}
+
+// unhold gives back the Handler slot held by `command`, if any.
+func (c *Handler) unhold(command *Command) {
+	command.status.Lock()
+	defer command.status.Unlock()
+	if command.status.release != nil {
+		command.status.release()
+		command.status.release = nil
+	}
+}
+
+// enqueue handles `command` in its own goroutine, after any other
+// command in the same thread, so commands in a thread run in order
+// while commands in different threads run concurrently. Unordered
+// commands don't wait for the rest of the thread.
+func (c *Handler) enqueue(command *Command) {
+	thread := command.Message().ThreadID()
+
+	c.threadsMutex.Lock()
+	defer c.threadsMutex.Unlock()
+	if c.stopped {
+		log.Printf("Ignoring message while shutting down: %v", command.Message().Text())
+		return
+	}
+	if c.unordered(command) {
+		c.inFlight.Add(1)
+		go func() {
+			defer c.inFlight.Done()
+			c.Dispatch(command)
+		}()
+		return
+	}
+	if queue, ok := c.threads[thread]; ok {
+		c.threads[thread] = append(queue, command)
+		return
+	}
+	c.threads[thread] = []*Command{}
+	c.inFlight.Add(1)
+	go c.drain(thread, command)
+}
+
+// unordered reports whether all the commands `command` runs are
+// Unordered, so it doesn't have to wait for the rest of commands in
+// its thread.
+func (c *Handler) unordered(command *Command) bool {
+	bound, err := c.Route(command.Message())
+	if err != nil {
+		return false
+	}
+	for _, b := range bound {
+		if r := c.find(b.Name()); r == nil || !r.spec.Unordered {
+			return false
+		}
+	}
+	return len(bound) > 0
+}
+
+// drain handles `command` and the rest of commands enqueued in
+// `thread` after it, one after another.
+func (c *Handler) drain(thread string, command *Command) {
+	defer c.inFlight.Done()
+	for command != nil {
+		c.Dispatch(command)
+
+		c.threadsMutex.Lock()
+		queue := c.threads[thread]
+		if len(queue) == 0 {
+			delete(c.threads, thread)
+			command = nil
+		} else {
+			command, c.threads[thread] = queue[0], queue[1:]
+		}
+		c.threadsMutex.Unlock()
+	}
+}
@@ -0,0 +1,211 @@
+package command
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+func message(text, thread string) synthetic.Message {
+	msg := synthetic.NewMockMessage(text, true)
+	msg.SetThreadID(thread)
+	return msg
+}
+
+// waitFor polls `condition` until it's true, failing the test if it
+// takes too long.
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestEventLoopConcurrency(t *testing.T) {
+	disableLogs()
+	h := NewHandler()
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	err := h.RegisterCommand("wait", Spec{Verb: "wait", Args: []Arg{{Name: "id"}}}, func(c *Command) error {
+		started <- c.Arg("id")
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := make(chan synthetic.Message)
+	done := make(chan struct{})
+	go func() {
+		h.EventLoop(messages)
+		close(done)
+	}()
+
+	messages <- message("wait 1", "C1/1")
+	messages <- message("wait 2", "C1/2")
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("commands in different threads should run concurrently")
+		}
+	}
+	close(release)
+	close(messages)
+	<-done
+}
+
+func TestEventLoopThreadOrder(t *testing.T) {
+	disableLogs()
+	h := NewHandler()
+	var m sync.Mutex
+	order := []string{}
+	err := h.RegisterCommand("wait", Spec{Verb: "wait", Args: []Arg{{Name: "id"}}}, func(c *Command) error {
+		if c.Arg("id") == "1" {
+			time.Sleep(20 * time.Millisecond)
+		}
+		m.Lock()
+		defer m.Unlock()
+		order = append(order, c.Arg("id"))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := make(chan synthetic.Message, 3)
+	messages <- message("wait 1", "C1/1")
+	messages <- message("wait 2", "C1/1")
+	messages <- message("wait 3", "C1/1")
+	close(messages)
+
+	h.EventLoop(messages)
+
+	expected := []string{"1", "2", "3"}
+	for i, id := range expected {
+		if order[i] != id {
+			t.Errorf("wrong order %v should be %v", order, expected)
+			break
+		}
+	}
+}
+
+func TestEventLoopLimits(t *testing.T) {
+	disableLogs()
+	tt := map[string]struct {
+		concurrency   int
+		maxConcurrent int
+	}{
+		"Global limit": {
+			concurrency: 1,
+		},
+		"Command limit": {
+			maxConcurrent: 1,
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			h := NewHandler()
+			h.SetConcurrency(tc.concurrency)
+			running := make(chan struct{}, 2)
+			release := make(chan struct{})
+			err := h.RegisterCommand(
+				"wait",
+				Spec{Verb: "wait", Args: []Arg{{Name: "id"}}, MaxConcurrent: tc.maxConcurrent},
+				func(c *Command) error {
+					running <- struct{}{}
+					<-release
+					return nil
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			messages := make(chan synthetic.Message)
+			done := make(chan struct{})
+			go func() {
+				h.EventLoop(messages)
+				close(done)
+			}()
+
+			messages <- message("wait 1", "C1/1")
+			<-running
+			messages <- message("wait 2", "C1/2")
+			waitFor(t, func() bool { return h.QueueDepth() == 1 })
+			if len(running) != 0 {
+				t.Error("second command shouldn't run before the first completes")
+			}
+
+			release <- struct{}{}
+			<-running
+			waitFor(t, func() bool { return h.QueueDepth() == 0 })
+			release <- struct{}{}
+			close(messages)
+			<-done
+		})
+	}
+}
+
+func TestEventLoopStarvation(t *testing.T) {
+	disableLogs()
+	tt := map[string]struct {
+		concurrency int
+		text        string
+	}{
+		"Waiting for the command limit": {
+			concurrency: 2,
+			text:        "wait",
+		},
+		"Waiting for an answer": {
+			concurrency: 1,
+			text:        "ask",
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			h := NewHandler()
+			h.SetConcurrency(tc.concurrency)
+			h.SetSessionTimeout(200 * time.Millisecond)
+			release := make(chan struct{})
+			specs := map[string]Spec{
+				"wait":  {Verb: "wait", Args: []Arg{{Name: "id"}}, MaxConcurrent: 1},
+				"ask":   {Verb: "ask", Args: []Arg{{Name: "id"}}},
+				"other": {Verb: "other"},
+			}
+			executors := map[string]ExecutorFunc{
+				"wait": func(c *Command) error {
+					<-release
+					return nil
+				},
+				"ask": func(c *Command) error {
+					_, err := c.Session().Ask("What?")
+					return err
+				},
+				"other": func(c *Command) error {
+					c.Message().Reply("done", false)
+					return nil
+				},
+			}
+			for name, spec := range specs {
+				if err := h.RegisterCommand(name, spec, executors[name]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			messages, stop := startLoop(h)
+			defer stop()
+			defer close(release)
+
+			messages <- message(tc.text+" 1", "C1/1")
+			messages <- message(tc.text+" 2", "C1/2")
+			waitFor(t, func() bool { return len(h.Running()) == 2 })
+			other := message("other", "C1/3").(*synthetic.MockMessage)
+			messages <- other
+			waitFor(t, func() bool { return len(other.Replies()) == 1 })
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultConfirmationTimeout is how long the Handler waits for the
+// users to confirm a command, unless changed with
+// SetConfirmationTimeout.
+const defaultConfirmationTimeout = time.Minute
+
+// approvals are the reactions confirming a command.
+var approvals = []string{"white_check_mark", "heavy_check_mark", "+1"}
+
+// SetConfirmationTimeout sets how long the Handler waits for the
+// users to confirm the commands requiring it.
+func (c *Handler) SetConfirmationTimeout(timeout time.Duration) {
+	c.confirmationTimeout = timeout
+}
+
+// requiresConfirmation reports whether `command` has to be confirmed
+// before it runs.
+func requiresConfirmation(command *Command) bool {
+	spec := command.Spec()
+	if spec == nil || !spec.Confirm {
+		return false
+	}
+	return spec.ConfirmIf == nil || spec.ConfirmIf(command)
+}
+
+// confirmed wraps `executor` so it only runs after the user confirms
+// the command, when it requires confirmation.
+func (c *Handler) confirmed(executor ExecutorFunc) ExecutorFunc {
+	return func(command *Command) error {
+		if !requiresConfirmation(command) {
+			return executor(command)
+		}
+		if err := c.confirm(command); err != nil {
+			return err
+		}
+		return executor(command)
+	}
+}
+
+// confirm replies with a summary of `command` and waits for the user
+// to confirm it answering `yes`, or reacting to the message with any
+// of the approvals. It returns ErrSessionCancelled if the user
+// answers anything else, and fails right away if the message is
+// unattended.
+func (c *Handler) confirm(command *Command) error {
+	msg := command.Message()
+	if msg.Unattended() {
+		return fmt.Errorf("`%s` has to be confirmed, so it can't run unattended", msg.Text())
+	}
+	summary := fmt.Sprintf("I'm going to run `%s`", msg.Text())
+	if command.Spec().Summary != "" {
+		summary = fmt.Sprintf("%s (%s)", summary, command.Spec().Summary)
+	}
+	msg.Reply(fmt.Sprintf(
+		"%s. Answer `yes` or react to your message with :%s: within %v to confirm it",
+		summary,
+		approvals[0],
+		c.confirmationTimeout,
+	), msg.Thread())
+
+	ctx, cancel := withTimeout(command.Context(), c.confirmationTimeout)
+	defer cancel()
+	a, err := c.await(ctx, command, Confirming, true)
+	if err != nil {
+		if command.Context().Err() != nil {
+			return fmt.Errorf("`#%d` was cancelled before it was confirmed: %w", command.ID(), command.Context().Err())
+		}
+		return fmt.Errorf("`%s` wasn't confirmed within %v, so I cancelled it", msg.Text(), c.confirmationTimeout)
+	}
+	if !a.approves() {
+		return ErrSessionCancelled
+	}
+	command.setState(Running)
+	return nil
+}
+
+// approves reports whether the answer is a `yes` or an approval.
+func (a answer) approves() bool {
+	if a.reaction != nil {
+		return Contains(approvals, a.reaction.Name())
+	}
+	return strings.EqualFold(strings.TrimSpace(a.message.Text()), "yes")
+}
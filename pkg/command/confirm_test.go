@@ -0,0 +1,149 @@
+package command
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+func TestConfirmation(t *testing.T) {
+	disableLogs()
+	alice := synthetic.NewMockUser("U000001", "@alice")
+	bob := synthetic.NewMockUser("U000002", "@bob")
+	tt := map[string]struct {
+		text       string
+		unattended bool
+		answer     string
+		user       synthetic.MockUser
+		reaction   string
+		replies    []string
+	}{
+		"Confirmed": {
+			text:   "deploy production",
+			answer: "yes",
+			user:   alice,
+			replies: []string{
+				"I'm going to run `deploy production` (Deploys the app). Answer `yes` or react to your message with :white_check_mark: within 100ms to confirm it",
+				"deployed production",
+			},
+		},
+		"Confirmed with a reaction": {
+			text:     "deploy production",
+			reaction: "+1",
+			user:     alice,
+			replies: []string{
+				"I'm going to run `deploy production` (Deploys the app). Answer `yes` or react to your message with :white_check_mark: within 100ms to confirm it",
+				"deployed production",
+			},
+		},
+		"Rejected": {
+			text:   "deploy production",
+			answer: "no",
+			user:   alice,
+			replies: []string{
+				"I'm going to run `deploy production` (Deploys the app). Answer `yes` or react to your message with :white_check_mark: within 100ms to confirm it",
+				"OK, I cancelled `deploy production`",
+			},
+		},
+		"Confirmed by someone else": {
+			text:   "deploy production",
+			answer: "yes",
+			user:   bob,
+			replies: []string{
+				"I'm going to run `deploy production` (Deploys the app). Answer `yes` or react to your message with :white_check_mark: within 100ms to confirm it",
+				"`deploy production` wasn't confirmed within 100ms, so I cancelled it",
+			},
+		},
+		"Unattended": {
+			text:       "deploy production",
+			unattended: true,
+			replies:    []string{"`deploy production` has to be confirmed, so it can't run unattended"},
+		},
+		"Not requiring confirmation": {
+			text:    "deploy staging",
+			replies: []string{"deployed staging"},
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			h := NewHandler()
+			h.SetConfirmationTimeout(100 * time.Millisecond)
+			err := h.RegisterCommand(
+				"deploy",
+				Spec{
+					Verb:      "deploy",
+					Args:      []Arg{{Name: "environment", Required: true}},
+					Confirm:   true,
+					ConfirmIf: func(c *Command) bool { return c.Arg("environment") == "production" },
+					Summary:   "Deploys the app",
+				},
+				func(c *Command) error {
+					c.Message().Reply("deployed "+c.Arg("environment"), false)
+					return nil
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			messages, stop := startLoop(h)
+			defer stop()
+			reactions := make(chan synthetic.Reaction)
+			defer close(reactions)
+			go h.ReactionLoop(reactions)
+
+			msg := synthetic.NewMockMessage(tc.text, true)
+			msg.SetID("C1/1")
+			msg.SetThreadID("C1/1")
+			msg.SetUser(alice)
+			msg.SetUnattended(tc.unattended)
+			messages <- msg
+			if len(tc.replies) > 1 {
+				waitFor(t, func() bool { return len(msg.Replies()) == 1 })
+			}
+			if tc.answer != "" {
+				answer := synthetic.NewMockMessage(tc.answer, false)
+				answer.SetThreadID("C1/1")
+				answer.SetUser(tc.user)
+				messages <- answer
+			}
+			if tc.reaction != "" {
+				reactions <- synthetic.NewMockReaction(tc.reaction, "C1/1", tc.user)
+			}
+			waitFor(t, func() bool { return len(msg.Replies()) == len(tc.replies) })
+
+			for i, reply := range tc.replies {
+				if msg.Replies()[i] != reply {
+					t.Errorf("wrong reply `%s` should be `%s`", msg.Replies()[i], reply)
+				}
+			}
+		})
+	}
+}
+
+func TestConfirmationCancelled(t *testing.T) {
+	disableLogs()
+	h := NewHandler()
+	err := h.RegisterCommand("deploy", Spec{Verb: "deploy", Confirm: true}, func(c *Command) error {
+		t.Error("cancelled command was run")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages, stop := startLoop(h)
+	defer stop()
+
+	msg := message("deploy", "C1/1").(*synthetic.MockMessage)
+	messages <- msg
+	waitFor(t, func() bool { return len(h.Running()) == 1 && h.Running()[0].State() == Confirming })
+	if _, err := h.Cancel(h.Running()[0].ID()); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return len(msg.Replies()) == 2 })
+
+	if reply := msg.Replies()[1]; !strings.HasPrefix(reply, "`#1` was cancelled before it was confirmed") {
+		t.Errorf("wrong reply `%s`", reply)
+	}
+}
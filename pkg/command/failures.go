@@ -0,0 +1,108 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+)
+
+// PanicError is the error an ExecutorFunc panicking is turned into.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// recovered returns the PanicError for a value returned by recover,
+// or nil if there was no panic.
+func recovered(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	return &PanicError{Value: value, Stack: debug.Stack()}
+}
+
+// execute runs `executor` for `command`, turning any panic into an
+// error, and reporting the error, if any, to the user.
+func (c *Handler) execute(command *Command, executor ExecutorFunc) (err error) {
+	defer func() {
+		if panicErr := recovered(recover()); panicErr != nil {
+			err = panicErr
+		}
+		if err != nil {
+			c.report(command, err)
+		}
+	}()
+	return executor(command)
+}
+
+// report logs the error from running `command`, counts it, and lets
+// the user know about it, reacting and replying to the message.
+// Denials, throttled commands and cancelled commands aren't failures,
+// so they're not counted. Only the user sees the replies, except for
+// the cancellations and the errors of the commands with PublicErrors.
+func (c *Handler) report(command *Command, err error) {
+	msg := command.Message()
+	var deniedErr *DeniedError
+	if errors.As(err, &deniedErr) {
+		log.Printf("Denied %v to %v: %v", command.Name(), deniedErr.User, err)
+		msg.React("no_entry")
+		msg.ReplyEphemeral(err.Error(), msg.Thread())
+		return
+	}
+	var throttledErr *ThrottledError
+	if errors.As(err, &throttledErr) {
+		log.Printf("Throttled %v for %v: %v", command.Name(), msg.User().Name(), err)
+		msg.React("snail")
+		msg.ReplyEphemeral(err.Error(), msg.Thread())
+		return
+	}
+	if errors.Is(err, ErrSessionCancelled) {
+		msg.React("octagonal_sign")
+		msg.Reply(fmt.Sprintf("OK, I cancelled `%s`", msg.Text()), msg.Thread())
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		log.Printf("Cancelled %v: %v", command.Name(), err)
+		msg.React("octagonal_sign")
+		msg.Reply(err.Error(), msg.Thread())
+		return
+	}
+
+	c.failuresMutex.Lock()
+	c.failures[command.Name()]++
+	c.failuresMutex.Unlock()
+
+	msg.React("boom")
+
+	text := err.Error()
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		log.Printf("Panic running %v: %v\n%s", command.Name(), panicErr.Value, panicErr.Stack)
+		text = fmt.Sprintf("Something went wrong running `%s`. Check my logs for the details", command.Name())
+	} else {
+		log.Printf("Error running %v: %v", command.Name(), err)
+	}
+	if command.Spec() != nil && command.Spec().PublicErrors {
+		msg.Reply(text, msg.Thread())
+		return
+	}
+	msg.ReplyEphemeral(text, msg.Thread())
+}
+
+// Failures returns the number of times each command failed, either
+// returning an error or panicking.
+func (c *Handler) Failures() map[string]int {
+	c.failuresMutex.Lock()
+	defer c.failuresMutex.Unlock()
+	failures := map[string]int{}
+	for name, count := range c.failures {
+		failures[name] = count
+	}
+	return failures
+}
@@ -0,0 +1,114 @@
+package command
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+func TestFailures(t *testing.T) {
+	disableLogs()
+	tt := map[string]struct {
+		text     string
+		replies  []string
+		public   bool
+		failures map[string]int
+	}{
+		"Error": {
+			text:     "fail",
+			replies:  []string{"the job `x` doesn't exist"},
+			failures: map[string]int{"fail": 1},
+		},
+		"Public error": {
+			text:     "build",
+			replies:  []string{"job `x` completed with `FAILURE`"},
+			public:   true,
+			failures: map[string]int{"build": 1},
+		},
+		"Denied": {
+			text:     "deny",
+			replies:  []string{"Sorry @alice, you're not allowed to run `deny`. Ask an admin if you need it"},
+			failures: map[string]int{},
+		},
+		"Unknown command": {
+			text:     "faill",
+			replies:  []string{"I don't know how to `faill`. Did you mean `fail`?"},
+			failures: map[string]int{},
+		},
+		"Panic": {
+			text:     "panic",
+			replies:  []string{"Something went wrong running `panic`. Check my logs for the details"},
+			failures: map[string]int{"panic": 1},
+		},
+		"Panic in passive listener": {
+			text:     "anything",
+			replies:  []string{"Something went wrong running `listener`. Check my logs for the details"},
+			failures: map[string]int{"listener": 1},
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			h := NewHandler()
+			h.SetRouting(FirstMatch)
+			err := h.Register("listener", func(c *Command) error {
+				if c.Message().Text() == "anything" {
+					var m map[string]int
+					m["nil"]++
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = h.RegisterCommand("fail", Spec{Verb: "fail"}, func(*Command) error {
+				return fmt.Errorf("the job `x` doesn't exist")
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = h.RegisterCommand("panic", Spec{Verb: "panic"}, func(*Command) error {
+				panic("boom")
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = h.RegisterCommand("build", Spec{Verb: "build", PublicErrors: true}, func(*Command) error {
+				return fmt.Errorf("job `x` completed with `FAILURE`")
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = h.RegisterCommand("deny", Spec{Verb: "deny"}, func(*Command) error {
+				return &DeniedError{User: "@alice", Command: "deny"}
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg := synthetic.NewMockMessage(tc.text, tc.text != "anything")
+
+			h.Dispatch(NewCommand(msg))
+
+			if len(msg.Replies()) != len(tc.replies) {
+				t.Fatalf("wrong replies %v should be %v", msg.Replies(), tc.replies)
+			}
+			for i, reply := range tc.replies {
+				if msg.Replies()[i] != reply {
+					t.Errorf("wrong reply `%s` should be `%s`", msg.Replies()[i], reply)
+				}
+			}
+			if ephemeral := len(msg.Ephemeral()) > 0; ephemeral == tc.public {
+				t.Errorf("wrong ephemeral replies %v for public %v", msg.Ephemeral(), tc.public)
+			}
+			failures := h.Failures()
+			if len(failures) != len(tc.failures) {
+				t.Errorf("wrong failures %v should be %v", failures, tc.failures)
+			}
+			for name, count := range tc.failures {
+				if failures[name] != count {
+					t.Errorf("wrong failures for %s %v should be %v", name, failures[name], count)
+				}
+			}
+		})
+	}
+}
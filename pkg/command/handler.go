@@ -1,49 +1,327 @@
 package command
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/ifosch/synthetic/pkg/synthetic"
 )
 
-// ExecutorFunc is the signature of any Command Executor
-type ExecutorFunc func(*Command)
+// ExecutorFunc is the signature of any Command Executor. The error
+// returned, if any, is reported to the user.
+type ExecutorFunc func(*Command) error
+
+// registration is an Executor registered with a Spec
+type registration struct {
+	name     string
+	spec     *Spec
+	executor ExecutorFunc
+	slots    chan struct{}
+}
 
 // Handler routes the individual Command instances to execution
 type Handler struct {
-	inventory map[string]ExecutorFunc
+	inventory   map[string]ExecutorFunc
+	commands    []*registration
+	routing     Routing
+	middlewares []Middleware
+	timeout     time.Duration
+
+	failuresMutex sync.Mutex
+	failures      map[string]int
+
+	slots        chan struct{}
+	queued       int32
+	threadsMutex sync.Mutex
+	threads      map[string][]*Command
+	inFlight     sync.WaitGroup
+	stopped      bool
+	stopping     chan struct{}
+
+	registryMutex sync.Mutex
+	registry      map[int]*Command
+	lastID        int
+
+	waitersMutex        sync.Mutex
+	waiters             []*waiter
+	confirmationTimeout time.Duration
+	sessionTimeout      time.Duration
+
+	auditLog *AuditLog
+	auditors *Policy
+
+	aliases *Aliases
 }
 
-// NewHandler returns a default Handler
+// NewHandler returns a default Handler, including the built-in `help`,
+// `jobs` and `cancel` commands.
 func NewHandler() *Handler {
-	return &Handler{
+	h := &Handler{
 		inventory: make(map[string]ExecutorFunc),
+		commands:  []*registration{},
+		failures:  map[string]int{},
+		threads:   map[string][]*Command{},
+		stopping:  make(chan struct{}),
+		registry:  map[int]*Command{},
+
+		confirmationTimeout: defaultConfirmationTimeout,
+		sessionTimeout:      defaultSessionTimeout,
 	}
+	h.commands = append(
+		h.commands,
+		&registration{name: "command.help", spec: &helpSpec, executor: h.help},
+		&registration{name: "command.jobs", spec: &jobsSpec, executor: h.jobs},
+		&registration{name: "command.cancel", spec: &cancelSpec, executor: h.cancel},
+	)
+	return h
 }
 
-// Register adds an Executor with a name to the existing Handler
+// Register adds an Executor with a name to the existing
+// Handler. These Executors are passive listeners run for every
+// message received, whatever the routing is.
 func (c *Handler) Register(name string, executor ExecutorFunc) error {
-	if _, ok := c.inventory[name]; ok {
+	if c.registered(name) {
 		return fmt.Errorf("command already registered under `%s` name", name)
 	}
 	c.inventory[name] = executor
 	return nil
 }
 
-// Dispatch routes a Command through all registered Executors
+// RegisterCommand adds an Executor with a name to the existing
+// Handler. This Executor is only run for messages mentioning the bot
+// and following the grammar declared in `spec`.
+func (c *Handler) RegisterCommand(name string, spec Spec, executor ExecutorFunc) error {
+	if c.registered(name) {
+		return fmt.Errorf("command already registered under `%s` name", name)
+	}
+	if spec.Verb == "" {
+		return fmt.Errorf("command `%s` has no verb", name)
+	}
+	c.commands = append(c.commands, &registration{
+		name:     name,
+		spec:     &spec,
+		executor: executor,
+		slots:    newSlots(spec.MaxConcurrent),
+	})
+	return nil
+}
+
+func (c *Handler) registered(name string) bool {
+	return c.lookup(name) != nil
+}
+
+// matching returns the commands whose verb and subcommands match
+// `tokens`, and whose Match function, if any, accepts `command`. The
+// Match functions are ignored when `command` is nil.
+func (c *Handler) matching(tokens []string, command *Command) []*registration {
+	matching := []*registration{}
+	for _, r := range c.commands {
+		if !r.spec.Matches(tokens) {
+			continue
+		}
+		if command != nil && r.spec.Match != nil && !r.spec.Match(command) {
+			continue
+		}
+		matching = append(matching, r)
+	}
+	return matching
+}
+
+// mostSpecific returns the commands with more words in `commands`,
+// so `list pods` isn't taken as `list` with an unexpected argument.
+func mostSpecific(commands []*registration) []*registration {
+	result := []*registration{}
+	longest := 0
+	for _, r := range commands {
+		words := len(r.spec.Words())
+		if words > longest {
+			result = []*registration{}
+			longest = words
+		}
+		if words == longest {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// route returns the commands whose grammar is followed by `command`,
+// once its alias is expanded, bound to their parsed arguments,
+// according to the Handler routing. When some command matches the
+// verb and subcommands, but none of them matches the whole grammar, it
+// returns the first usage error found.
+func (c *Handler) route(command *Command) (bound []*Command, err error) {
+	if !command.Message().Mention() {
+		return nil, nil
+	}
+	if err := c.expand(command); err != nil {
+		return nil, err
+	}
+	if c.routing == FirstMatch {
+		first, err := c.routeFirst(command)
+		if err != nil {
+			return nil, err
+		}
+		return []*Command{first}, nil
+	}
+
+	for _, r := range mostSpecific(c.matching(command.Tokens(), command)) {
+		arguments, parseErr := r.spec.Parse(command.Tokens())
+		if parseErr != nil {
+			if err == nil {
+				err = parseErr
+			}
+			continue
+		}
+		bound = append(bound, command.bind(r.name, r.spec, arguments))
+	}
+	if len(bound) > 0 {
+		err = nil
+	}
+
+	return bound, err
+}
+
+// lookup returns the Executor registered as `name`.
+func (c *Handler) lookup(name string) ExecutorFunc {
+	if executor, ok := c.inventory[name]; ok {
+		return executor
+	}
+	if r := c.find(name); r != nil {
+		return r.executor
+	}
+	return nil
+}
+
+// find returns the command registered as `name`, if any.
+func (c *Handler) find(name string) *registration {
+	for _, r := range c.commands {
+		if r.name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// Dispatch routes a Command through all registered Executors. A
+// command chaining several ones, like `build a && build b`, runs them
+// one after another.
 func (c *Handler) Dispatch(command *Command) {
 	var wg sync.WaitGroup
 	for name, executor := range c.inventory {
 		wg.Add(1)
-		log.Printf("Invoking processor %v", name)
+		listener := command.bind(name, nil, nil)
+		log.Printf("Invoking processor %v", listener.Name())
 		go func(executor ExecutorFunc) {
-			executor(command)
-			wg.Done()
+			defer wg.Done()
+			ctx, cancel := withTimeout(listener.Context(), c.timeout)
+			defer cancel()
+			listener := listener.WithContext(ctx)
+			if err := c.hold(listener); err != nil {
+				return
+			}
+			defer c.unhold(listener)
+			c.execute(listener, executor)
 		}(executor)
 	}
+
+	steps, err := c.chain(command)
+	switch {
+	case !command.Message().Mention():
+	case command.tokenizeErr != nil:
+		command.Message().ReplyEphemeral(command.tokenizeErr.Error(), command.Message().Thread())
+	case err != nil:
+		command.Message().ReplyEphemeral(err.Error(), command.Message().Thread())
+	case len(steps) > 1:
+		c.runChain(command, steps)
+	default:
+		c.runRouted(command)
+	}
+
+	wg.Wait()
+}
+
+// runRouted routes `command` and runs the commands it's bound to,
+// returning the routing error or the first error returned by them.
+func (c *Handler) runRouted(command *Command) error {
+	bound, err := c.route(command)
+	if err != nil {
+		command.Message().ReplyEphemeral(err.Error(), command.Message().Thread())
+		return err
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(bound))
+	for i, command := range bound {
+		wg.Add(1)
+		log.Printf("Invoking processor %v", command.Name())
+		go func(i int, command *Command) {
+			defer wg.Done()
+			errs[i] = c.run(command, c.find(command.Name()))
+		}(i, command)
+	}
 	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run runs `command` with the executor in `r`, once it gets a
+// concurrency slot of `r`, and then one of the Handler, and returns
+// its error. Commands waiting for a slot of `r` don't hold any of the
+// Handler, so they don't starve other commands.
+func (c *Handler) run(command *Command, r *registration) error {
+	timeout := c.timeout
+	if r.spec.Timeout != 0 {
+		timeout = r.spec.Timeout
+	}
+	ctx, cancel := withTimeout(command.Context(), timeout)
+	defer cancel()
+	command = command.WithContext(ctx)
+	if r.spec.Overflow != "" {
+		command.message = command.message.WithOverflow(r.spec.Overflow)
+	}
+	command.session = &Session{handler: c, command: command}
+	unregister := c.register(command, cancel)
+	defer unregister()
+
+	started := time.Now()
+	release, err := c.acquire(ctx, r.slots, command, command.Name())
+	if err == nil {
+		defer release()
+		err = c.hold(command)
+	}
+	if err != nil {
+		err = fmt.Errorf("`#%d` was cancelled before it started: %w", command.ID(), err)
+		c.report(command, err)
+		c.record(command, started, err)
+		return err
+	}
+	defer c.unhold(command)
+	command.setState(Running)
+	err = c.execute(command, c.wrap(c.confirmed(r.executor)))
+	c.record(command, started, err)
+	return err
+}
+
+// SetTimeout sets the default timeout of the Executors. Commands can
+// override it in their Spec, and zero means no timeout.
+func (c *Handler) SetTimeout(timeout time.Duration) {
+	c.timeout = timeout
+}
+
+// withTimeout returns a context derived from `parent`, with
+// `timeout` if it isn't zero.
+func withTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout == 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, timeout)
 }
 
 // ParseMessage creates a Command from a synthetic.Message
@@ -51,18 +329,35 @@ func (c *Handler) ParseMessage(message synthetic.Message) (*Command, error) {
 	return NewCommand(message), nil
 }
 
-// EventLoop runs an infinite loop that reads messages from a channel
-// of synthetic.Message and calls Dispatch on each synthetic.Message
+// EventLoop runs a loop that reads messages from a channel of
+// synthetic.Message and dispatches each of them without waiting for
+// the previous ones to complete, except for the ones in the same
+// thread, unless they're Unordered. Messages answering a command waiting for its user are given
+// to it instead. It returns once the channel is closed and all the
+// messages read were handled, or as soon as Shutdown is called.
 func (c *Handler) EventLoop(messageChannel chan (synthetic.Message)) {
-	for message := range messageChannel {
-		command, err := c.ParseMessage(message)
-		if err != nil {
-			log.Printf(
-				"error parsing message: %v for message: %#v",
-				err.Error(),
-				message,
-			)
+	for {
+		select {
+		case <-c.stopping:
+			return
+		case message, ok := <-messageChannel:
+			if !ok {
+				c.inFlight.Wait()
+				return
+			}
+			if c.answerMessage(message) {
+				continue
+			}
+			command, err := c.ParseMessage(message)
+			if err != nil {
+				log.Printf(
+					"error parsing message: %v for message: %#v",
+					err.Error(),
+					message,
+				)
+				continue
+			}
+			c.enqueue(command)
 		}
-		c.Dispatch(command)
 	}
 }
@@ -0,0 +1,230 @@
+package command
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+// recorder keeps track of the commands run by the executors it
+// provides.
+type recorder struct {
+	sync.Mutex
+	runs map[string]*Command
+}
+
+func newRecorder() *recorder {
+	return &recorder{runs: map[string]*Command{}}
+}
+
+func (r *recorder) executor(name string) ExecutorFunc {
+	return func(c *Command) error {
+		r.Lock()
+		defer r.Unlock()
+		r.runs[name] = c
+		return nil
+	}
+}
+
+func testHandler(t *testing.T, r *recorder) *Handler {
+	h := NewHandler()
+	err := h.Register("listener", r.executor("listener"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	specs := map[string]Spec{
+		"list":          {Verb: "list"},
+		"list pods":     {Verb: "list", Subcommands: []string{"pods"}, Args: []Arg{{Name: "cluster"}}},
+		"build":         {Verb: "build", Args: []Arg{{Name: "job", Required: true}}, AnyParams: true},
+		"list clusters": {Verb: "list", Subcommands: []string{"clusters"}},
+	}
+	for name, spec := range specs {
+		if err := h.RegisterCommand(name, spec, r.executor(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return h
+}
+
+func TestRegister(t *testing.T) {
+	h := testHandler(t, newRecorder())
+	if err := h.Register("list", func(*Command) error { return nil }); err == nil {
+		t.Error("registering an existing name should fail")
+	}
+	if err := h.RegisterCommand("listener", Spec{Verb: "listen"}, func(*Command) error { return nil }); err == nil {
+		t.Error("registering an existing name should fail")
+	}
+	if err := h.RegisterCommand("noverb", Spec{}, func(*Command) error { return nil }); err == nil {
+		t.Error("registering a command without verb should fail")
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	tt := map[string]struct {
+		text    string
+		mention bool
+		runs    []string
+		replies []string
+	}{
+		"Passive listeners only": {
+			text:    "list",
+			mention: false,
+			runs:    []string{"listener"},
+		},
+		"Verb": {
+			text:    "list",
+			mention: true,
+			runs:    []string{"listener", "list"},
+		},
+		"Most specific command": {
+			text:    "list pods cluster1",
+			mention: true,
+			runs:    []string{"listener", "list pods"},
+		},
+		"No partial word matches": {
+			text:    "rebuild the docs",
+			mention: true,
+			runs:    []string{"listener"},
+		},
+		"Usage error": {
+			text:    "build",
+			mention: true,
+			runs:    []string{"listener"},
+			replies: []string{"missing argument `job`. Usage: `build <job> [KEY=VALUE...]`"},
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			r := newRecorder()
+			h := testHandler(t, r)
+			msg := synthetic.NewMockMessage(tc.text, tc.mention)
+			h.Dispatch(NewCommand(msg))
+
+			if len(r.runs) != len(tc.runs) {
+				t.Errorf("wrong number of executors run %v should be %v", len(r.runs), len(tc.runs))
+			}
+			for _, name := range tc.runs {
+				c, ok := r.runs[name]
+				if !ok {
+					t.Errorf("executor `%s` wasn't run", name)
+					continue
+				}
+				if c.Name() != name {
+					t.Errorf("wrong command name `%s` should be `%s`", c.Name(), name)
+				}
+			}
+			if len(msg.Replies()) != len(tc.replies) {
+				t.Fatalf("wrong number of replies %v should be %v", len(msg.Replies()), len(tc.replies))
+			}
+			for i, reply := range tc.replies {
+				if msg.Replies()[i] != reply {
+					t.Errorf("wrong reply `%s` should be `%s`", msg.Replies()[i], reply)
+				}
+			}
+		})
+	}
+}
+
+func TestDispatchArguments(t *testing.T) {
+	r := newRecorder()
+	h := testHandler(t, r)
+	h.Dispatch(NewCommand(synthetic.NewMockMessage("build deploy ENV=prod", true)))
+
+	c := r.runs["build"]
+	if c == nil {
+		t.Fatal("build wasn't run")
+	}
+	if c.Arg("job") != "deploy" {
+		t.Errorf("wrong job `%s` should be `deploy`", c.Arg("job"))
+	}
+	if c.Params()["ENV"] != "prod" {
+		t.Errorf("wrong ENV `%s` should be `prod`", c.Params()["ENV"])
+	}
+	if r.runs["listener"].Arg("job") != "" {
+		t.Error("passive listeners shouldn't get parsed arguments")
+	}
+}
+
+func TestDispatchTimeout(t *testing.T) {
+	tt := map[string]struct {
+		spec Spec
+	}{
+		"Handler default timeout": {
+			spec: Spec{Verb: "wait"},
+		},
+		"Command timeout": {
+			spec: Spec{Verb: "wait", Timeout: 10 * time.Millisecond},
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			h := NewHandler()
+			h.SetTimeout(time.Hour)
+			var deadline time.Time
+			var err error
+			err = h.RegisterCommand("wait", tc.spec, func(c *Command) error {
+				deadline, _ = c.Context().Deadline()
+				if tc.spec.Timeout != 0 {
+					<-c.Context().Done()
+					err = c.Context().Err()
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			h.Dispatch(NewCommand(synthetic.NewMockMessage("wait", true)))
+
+			timeout := tc.spec.Timeout
+			if timeout == 0 {
+				timeout = time.Hour
+			}
+			if deadline.IsZero() || time.Until(deadline) > timeout {
+				t.Errorf("deadline %v is later than timeout %v", deadline, timeout)
+			}
+			if tc.spec.Timeout != 0 && err != context.DeadlineExceeded {
+				t.Errorf("wrong context error %v should be %v", err, context.DeadlineExceeded)
+			}
+		})
+	}
+}
+
+func TestDispatchOverflow(t *testing.T) {
+	tt := map[string]struct {
+		text     string
+		spec     Spec
+		overflow synthetic.Overflow
+	}{
+		"Platform default": {
+			text: "list pods",
+			spec: Spec{Verb: "list", Subcommands: []string{"pods"}},
+		},
+		"Command overflow": {
+			text:     "list pods",
+			spec:     Spec{Verb: "list", Subcommands: []string{"pods"}, Overflow: synthetic.OverflowSnippet},
+			overflow: synthetic.OverflowSnippet,
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			h := NewHandler()
+			err := h.RegisterCommand("list", tc.spec, func(c *Command) error {
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg := synthetic.NewMockMessage(tc.text, true)
+
+			h.Dispatch(NewCommand(msg))
+
+			if msg.Overflow() != tc.overflow {
+				t.Errorf("wrong overflow `%s` should be `%s`", msg.Overflow(), tc.overflow)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CategoryChat is the category of the commands related to the chat
+// itself, like `help`.
+const CategoryChat = "chat"
+
+// uncategorized is the category used in help for commands registered
+// without one.
+const uncategorized = "other"
+
+// helpSpec is the Spec of the built-in help command.
+var helpSpec = Spec{
+	Verb:     "help",
+	Args:     []Arg{{Name: "command", Variadic: true}},
+	Summary:  "Lists the available commands, or describes one of them",
+	Examples: []string{"help", "help build"},
+	Category: CategoryChat,
+}
+
+// help replies privately with the list of registered commands
+// grouped by category, or with the details of the commands starting
+// with the words in the `command` argument.
+func (c *Handler) help(command *Command) error {
+	msg := command.Message()
+	if command.Arg("command") == "" {
+		msg.ReplyEphemeral(c.helpIndex(), msg.Thread())
+		return nil
+	}
+
+	// `help list` describes every `list` command, while `help
+	// build deploy` describes `build`.
+	words := strings.Fields(command.Arg("command"))
+	details := []string{}
+	for _, r := range c.commands {
+		if startsWith(r.spec.Words(), words) {
+			details = append(details, helpDetail(r.spec))
+		}
+	}
+	if len(details) == 0 {
+		for _, r := range mostSpecific(c.matching(words, nil)) {
+			details = append(details, helpDetail(r.spec))
+		}
+	}
+	if len(details) == 0 {
+		return fmt.Errorf("I don't know the `%s` command. Try `help` to get the list of commands", command.Arg("command"))
+	}
+	msg.ReplyEphemeral(strings.Join(details, "\n"), msg.Thread())
+	return nil
+}
+
+// helpIndex returns the summary of every registered command grouped
+// by category.
+func (c *Handler) helpIndex() string {
+	categories := map[string][]*Spec{}
+	for _, r := range c.commands {
+		category := r.spec.Category
+		if category == "" {
+			category = uncategorized
+		}
+		categories[category] = append(categories[category], r.spec)
+	}
+
+	names := []string{}
+	for category := range categories {
+		names = append(names, category)
+	}
+	sort.Strings(names)
+
+	result := "I know the following commands:\n"
+	for _, category := range names {
+		result = fmt.Sprintf("%s*%s*\n", result, category)
+		for _, spec := range categories[category] {
+			result = fmt.Sprintf("%s- `%s`: %s\n", result, spec.Synopsis(), spec.Summary)
+		}
+	}
+	return fmt.Sprintf("%sUse `help <command>` to get the details of a command.", result)
+}
+
+// helpDetail returns the full description of a command.
+func helpDetail(spec *Spec) string {
+	result := fmt.Sprintf("`%s`\n", spec.Synopsis())
+	if spec.Summary != "" {
+		result = fmt.Sprintf("%s%s\n", result, spec.Summary)
+	}
+	if len(spec.Examples) > 0 {
+		result = fmt.Sprintf("%sExamples:\n", result)
+		for _, example := range spec.Examples {
+			result = fmt.Sprintf("%s- `%s`\n", result, example)
+		}
+	}
+	return result
+}
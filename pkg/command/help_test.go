@@ -0,0 +1,90 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+func helpHandler(t *testing.T) *Handler {
+	h := NewHandler()
+	specs := map[string]Spec{
+		"jenkins.List": {Verb: "list", Summary: "Lists the jobs", Category: "jenkins"},
+		"k8s.listPods": {
+			Verb:        "list",
+			Subcommands: []string{"pods"},
+			Args:        []Arg{{Name: "cluster"}},
+			Summary:     "Lists the pods",
+			Examples:    []string{"list pods cluster1"},
+			Category:    "k8s",
+		},
+		"misc": {Verb: "misc"},
+	}
+	for name, spec := range specs {
+		if err := h.RegisterCommand(name, spec, func(*Command) error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return h
+}
+
+func TestHelp(t *testing.T) {
+	tt := map[string]struct {
+		text     string
+		contains []string
+		excludes []string
+	}{
+		"Index": {
+			text: "help",
+			contains: []string{
+				"*chat*\n- `help [<command>...]`: Lists the available commands, or describes one of them\n",
+				"*jenkins*\n- `list`: Lists the jobs\n",
+				"*k8s*\n- `list pods [<cluster>]`: Lists the pods\n",
+				"*other*\n- `misc`: \n",
+			},
+		},
+		"Every command starting with the words": {
+			text:     "help list",
+			contains: []string{"`list`\nLists the jobs\n", "`list pods [<cluster>]`\nLists the pods\nExamples:\n- `list pods cluster1`\n"},
+		},
+		"Specific command": {
+			text:     "help list pods",
+			contains: []string{"`list pods [<cluster>]`"},
+			excludes: []string{"`list`\n"},
+		},
+		"Command with arguments": {
+			text:     "help list pods cluster1",
+			contains: []string{"`list pods [<cluster>]`"},
+			excludes: []string{"`list`\n"},
+		},
+		"Unknown command": {
+			text:     "help deploy",
+			contains: []string{"I don't know the `deploy` command"},
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			h := helpHandler(t)
+			msg := synthetic.NewMockMessage(tc.text, true)
+			h.Dispatch(NewCommand(msg))
+
+			if len(msg.Replies()) != 1 {
+				t.Fatalf("wrong number of replies %v should be 1", len(msg.Replies()))
+			}
+			if len(msg.Ephemeral()) != 1 {
+				t.Errorf("the help should only be visible to its user")
+			}
+			for _, expected := range tc.contains {
+				if !strings.Contains(msg.Replies()[0], expected) {
+					t.Errorf("`%s` not found in help `%s`", expected, msg.Replies()[0])
+				}
+			}
+			for _, unexpected := range tc.excludes {
+				if strings.Contains(msg.Replies()[0], unexpected) {
+					t.Errorf("`%s` found in help `%s`", unexpected, msg.Replies()[0])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package command
+
+import (
+	"log"
+	"time"
+)
+
+// Middleware wraps an ExecutorFunc to add behavior around it. It can
+// inspect the Command before and after calling the wrapped
+// ExecutorFunc, or not call it at all to short-circuit the Command.
+type Middleware func(ExecutorFunc) ExecutorFunc
+
+// Use adds middlewares to wrap the Executors of the commands routed by
+// the Handler. Middlewares run in the order they were added, so the
+// first one is the outermost.
+func (c *Handler) Use(middlewares ...Middleware) {
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// wrap returns `executor` wrapped by all the Handler middlewares.
+func (c *Handler) wrap(executor ExecutorFunc) ExecutorFunc {
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		executor = c.middlewares[i](executor)
+	}
+	return executor
+}
+
+// Logging is a Middleware logging every command run and how long it
+// took.
+func Logging() Middleware {
+	return func(next ExecutorFunc) ExecutorFunc {
+		return func(c *Command) error {
+			msg := c.Message()
+			log.Printf(
+				"Running %v for '%v' in '%v': '%v'",
+				c.Name(),
+				msg.User().Name(),
+				msg.Conversation().Name(),
+				msg.Text(),
+			)
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				log.Printf("Failed %v after %v: %v", c.Name(), time.Since(start), err)
+				return err
+			}
+			log.Printf("Finished %v after %v", c.Name(), time.Since(start))
+			return nil
+		}
+	}
+}
+
+// Recovery is a Middleware turning any panic in the wrapped
+// ExecutorFunc into a *PanicError, so the outer middlewares see it as
+// any other error. Dispatch recovers from panics anyway, so this is
+// only needed for middlewares to be aware of them.
+func Recovery() Middleware {
+	return func(next ExecutorFunc) ExecutorFunc {
+		return func(c *Command) (err error) {
+			defer func() {
+				if panicErr := recovered(recover()); panicErr != nil {
+					err = panicErr
+				}
+			}()
+			return next(c)
+		}
+	}
+}
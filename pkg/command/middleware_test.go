@@ -0,0 +1,123 @@
+package command
+
+import (
+	"io"
+	"log"
+	"sync"
+	"testing"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+func disableLogs() {
+	log.SetFlags(0)
+	log.SetOutput(io.Discard)
+}
+
+// tracer is a Middleware recording the order of the calls around the
+// wrapped ExecutorFunc.
+type tracer struct {
+	sync.Mutex
+	calls []string
+}
+
+func (tr *tracer) trace(call string) {
+	tr.Lock()
+	defer tr.Unlock()
+	tr.calls = append(tr.calls, call)
+}
+
+func (tr *tracer) middleware(id string, shortCircuit bool) Middleware {
+	return func(next ExecutorFunc) ExecutorFunc {
+		return func(c *Command) error {
+			tr.trace(id + " before " + c.Name())
+			if shortCircuit {
+				return nil
+			}
+			err := next(c)
+			tr.trace(id + " after " + c.Name())
+			return err
+		}
+	}
+}
+
+func TestMiddlewares(t *testing.T) {
+	disableLogs()
+	tt := map[string]struct {
+		shortCircuit bool
+		calls        []string
+	}{
+		"Ordered": {
+			shortCircuit: false,
+			calls: []string{
+				"outer before build",
+				"inner before build",
+				"executor",
+				"inner after build",
+				"outer after build",
+			},
+		},
+		"Short-circuit": {
+			shortCircuit: true,
+			calls: []string{
+				"outer before build",
+				"inner before build",
+				"outer after build",
+			},
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			tr := &tracer{}
+			h := NewHandler()
+			h.Use(tr.middleware("outer", false), tr.middleware("inner", tc.shortCircuit))
+			err := h.Register("listener", func(*Command) error { return nil })
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = h.RegisterCommand("build", Spec{Verb: "build"}, func(*Command) error {
+				tr.trace("executor")
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			h.Dispatch(NewCommand(synthetic.NewMockMessage("build", true)))
+
+			if len(tr.calls) != len(tc.calls) {
+				t.Fatalf("wrong calls %v should be %v", tr.calls, tc.calls)
+			}
+			for i, call := range tc.calls {
+				if tr.calls[i] != call {
+					t.Errorf("wrong call `%s` should be `%s`", tr.calls[i], call)
+				}
+			}
+		})
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	disableLogs()
+	var seen error
+	h := NewHandler()
+	h.Use(
+		func(next ExecutorFunc) ExecutorFunc {
+			return func(c *Command) error {
+				seen = next(c)
+				return seen
+			}
+		},
+		Recovery(),
+	)
+	err := h.RegisterCommand("panic", Spec{Verb: "panic"}, func(*Command) error { panic("boom") })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.Dispatch(NewCommand(synthetic.NewMockMessage("panic", true)))
+
+	if _, ok := seen.(*PanicError); !ok {
+		t.Errorf("outer middleware should see a *PanicError but got %#v", seen)
+	}
+}
@@ -0,0 +1,117 @@
+package command
+
+import (
+	"context"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+// answer is what a user answered to a question from the bot: either
+// a message in the same thread, or a reaction to the message with
+// the Command.
+type answer struct {
+	message  synthetic.Message
+	reaction synthetic.Reaction
+}
+
+// waiter is a Command waiting for the user who sent it to answer.
+type waiter struct {
+	thread string
+	// message is the ID of the message whose reactions are
+	// answers, if any.
+	message string
+	user    string
+	answers chan answer
+}
+
+// await waits for the user who sent `command` to answer in the same
+// thread, or reacting to the message of `command` if `reactions` is
+// true, until `ctx` is done. `command` is in `state` meanwhile, and
+// it doesn't hold its Handler slot, taking it back once answered.
+func (c *Handler) await(ctx context.Context, command *Command, state State, reactions bool) (answer, error) {
+	msg := command.Message()
+	w := &waiter{
+		thread:  msg.ThreadID(),
+		user:    msg.User().ID(),
+		answers: make(chan answer, 1),
+	}
+	if reactions {
+		w.message = msg.ID()
+	}
+	c.waitersMutex.Lock()
+	c.waiters = append(c.waiters, w)
+	c.waitersMutex.Unlock()
+	defer c.stopWaiting(w)
+	command.setState(state)
+	c.unhold(command)
+
+	select {
+	case a := <-w.answers:
+		return a, c.hold(command)
+	case <-ctx.Done():
+		return answer{}, ctx.Err()
+	}
+}
+
+// stopWaiting removes `w` from the waiters, if still there.
+func (c *Handler) stopWaiting(w *waiter) {
+	c.waitersMutex.Lock()
+	defer c.waitersMutex.Unlock()
+	for i, waiting := range c.waiters {
+		if waiting == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// deliver gives `a` to the first waiter accepting it, and reports
+// whether any did.
+func (c *Handler) deliver(a answer, accepts func(*waiter) bool) bool {
+	c.waitersMutex.Lock()
+	defer c.waitersMutex.Unlock()
+	for i, w := range c.waiters {
+		if accepts(w) {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			w.answers <- a
+			return true
+		}
+	}
+	return false
+}
+
+// answerMessage delivers `message` to the Command waiting for an
+// answer from its user in its thread, and reports whether there was
+// any. Such messages aren't handled as commands.
+func (c *Handler) answerMessage(message synthetic.Message) bool {
+	return c.deliver(answer{message: message}, func(w *waiter) bool {
+		return w.thread == message.ThreadID() && w.user == message.User().ID()
+	})
+}
+
+// answerReaction delivers `reaction` to the Command waiting for an
+// answer from its user to the message reacted to, and reports
+// whether there was any.
+func (c *Handler) answerReaction(reaction synthetic.Reaction) bool {
+	return c.deliver(answer{reaction: reaction}, func(w *waiter) bool {
+		return w.message != "" && w.message == reaction.MessageID() && w.user == reaction.User().ID()
+	})
+}
+
+// ReactionLoop runs a loop that reads reactions from a channel of
+// synthetic.Reaction and gives them to the commands waiting for
+// them. It returns once the channel is closed, or as soon as
+// Shutdown is called.
+func (c *Handler) ReactionLoop(reactionChannel chan (synthetic.Reaction)) {
+	for {
+		select {
+		case <-c.stopping:
+			return
+		case reaction, ok := <-reactionChannel:
+			if !ok {
+				return
+			}
+			c.answerReaction(reaction)
+		}
+	}
+}
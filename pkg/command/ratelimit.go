@@ -0,0 +1,157 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate is a token bucket limit: up to Burst commands at once, with one
+// more allowed every Every. The zero Rate doesn't limit anything.
+type Rate struct {
+	Burst int           `yaml:"burst"`
+	Every time.Duration `yaml:"every"`
+}
+
+// RateLimits are the Rates applied to the commands sent by each user,
+// in each conversation, and to each command, identified by its verb
+// and subcommands, like `build` or `list pods`.
+type RateLimits struct {
+	User         Rate            `yaml:"user"`
+	Conversation Rate            `yaml:"conversation"`
+	Commands     map[string]Rate `yaml:"commands"`
+}
+
+// ThrottledError is returned when a command exceeds any of the
+// RateLimits.
+type ThrottledError struct {
+	// Limit tells which limit was exceeded.
+	Limit      string
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf(
+		"Easy there! You reached the limit of commands %s. Try again in %v",
+		e.Limit,
+		e.RetryAfter.Round(time.Second),
+	)
+}
+
+// Validate checks that all the Rates have a refill period.
+func (l *RateLimits) Validate() error {
+	rates := map[string]Rate{"user": l.User, "conversation": l.Conversation}
+	for command, rate := range l.Commands {
+		rates[fmt.Sprintf("command `%s`", command)] = rate
+	}
+	for name, rate := range rates {
+		if rate.Burst > 0 && rate.Every <= 0 {
+			return fmt.Errorf("the rate limit for %s has no `every` period", name)
+		}
+	}
+	return nil
+}
+
+// bucket is the state of a token bucket.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens for the time passed since the last refill,
+// up to the burst of `rate`, and returns how long until there's a
+// token to take.
+func (b *bucket) refill(rate Rate, now time.Time) time.Duration {
+	b.tokens += float64(now.Sub(b.last)) / float64(rate.Every)
+	if b.tokens > float64(rate.Burst) {
+		b.tokens = float64(rate.Burst)
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) * float64(rate.Every))
+}
+
+// limiter keeps the token buckets of some RateLimits.
+type limiter struct {
+	sync.Mutex
+	limits  RateLimits
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// limited is a Rate applying to a Command, and the key of its bucket.
+type limited struct {
+	key   string
+	limit string
+	rate  Rate
+}
+
+// rates returns the Rates applying to `command`.
+func (l *limiter) rates(command *Command) []limited {
+	msg := command.Message()
+	words := strings.Join(command.Spec().Words(), " ")
+	rates := []limited{
+		{"user/" + msg.User().ID(), "per user", l.limits.User},
+		{"conversation/" + msg.Conversation().ID(), "in this conversation", l.limits.Conversation},
+		{"command/" + words, fmt.Sprintf("for `%s`", words), l.limits.Commands[words]},
+	}
+	result := []limited{}
+	for _, r := range rates {
+		if r.rate.Burst > 0 {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// take takes a token from all the buckets applying to `command`, or
+// none if any of them is empty, returning the *ThrottledError for the
+// one taking longer to refill.
+func (l *limiter) take(command *Command) error {
+	l.Lock()
+	defer l.Unlock()
+	now := l.now()
+	var throttled *ThrottledError
+	rates := l.rates(command)
+	for _, r := range rates {
+		b, ok := l.buckets[r.key]
+		if !ok {
+			b = &bucket{tokens: float64(r.rate.Burst), last: now}
+			l.buckets[r.key] = b
+		}
+		wait := b.refill(r.rate, now)
+		if wait > 0 && (throttled == nil || wait > throttled.RetryAfter) {
+			throttled = &ThrottledError{Limit: r.limit, RetryAfter: wait}
+		}
+	}
+	if throttled != nil {
+		return throttled
+	}
+	for _, r := range rates {
+		l.buckets[r.key].tokens--
+	}
+	return nil
+}
+
+// RateLimit is a Middleware only running the commands within
+// `limits`. Any other command is answered with a *ThrottledError.
+func RateLimit(limits RateLimits) Middleware {
+	l := &limiter{
+		limits:  limits,
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+	return l.middleware
+}
+
+func (l *limiter) middleware(next ExecutorFunc) ExecutorFunc {
+	return func(c *Command) error {
+		if err := l.take(c); err != nil {
+			return err
+		}
+		return next(c)
+	}
+}
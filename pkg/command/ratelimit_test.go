@@ -0,0 +1,128 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+func TestRateLimit(t *testing.T) {
+	disableLogs()
+	limits := RateLimits{
+		User:         Rate{Burst: 2, Every: 10 * time.Second},
+		Conversation: Rate{Burst: 3, Every: 5 * time.Second},
+		Commands:     map[string]Rate{"build": {Burst: 1, Every: time.Minute}},
+	}
+	alice := synthetic.NewMockUser("U000001", "@alice")
+	bob := synthetic.NewMockUser("U000002", "@bob")
+	general := synthetic.NewMockConversation("CH00001", "#general")
+	// other is a different conversation with the same name, as the
+	// names aren't unique, like the ones of the direct messages.
+	other := synthetic.NewMockConversation("CH00002", "#general")
+	type sent struct {
+		text         string
+		user         synthetic.MockUser
+		after        time.Duration
+		reply        string
+		conversation synthetic.MockConversation
+	}
+	tt := map[string][]sent{
+		"Per user": {
+			{"hello", alice, 0, "ran hello", general},
+			{"hello", alice, 0, "ran hello", general},
+			{"hello", alice, 0, "Easy there! You reached the limit of commands per user. Try again in 10s", general},
+			{"hello", bob, 0, "ran hello", general},
+			{"hello", alice, 6 * time.Second, "Easy there! You reached the limit of commands per user. Try again in 4s", general},
+			{"hello", alice, 4 * time.Second, "ran hello", general},
+		},
+		"Per conversation": {
+			{"hello", alice, 0, "ran hello", general},
+			{"hello", alice, 0, "ran hello", general},
+			{"hello", bob, 0, "ran hello", general},
+			{"hello", bob, 0, "Easy there! You reached the limit of commands in this conversation. Try again in 5s", general},
+			{"hello", bob, 5 * time.Second, "ran hello", general},
+		},
+		"Per conversation with the same name": {
+			{"hello", alice, 0, "ran hello", general},
+			{"hello", bob, 0, "ran hello", general},
+			{"hello", bob, 0, "ran hello", general},
+			{"hello", alice, 0, "ran hello", other},
+		},
+		"Per command": {
+			{"build", alice, 0, "ran build", general},
+			{"build", bob, 0, "Easy there! You reached the limit of commands for `build`. Try again in 1m0s", general},
+			{"hello", bob, 0, "ran hello", general},
+		},
+		"Throttled commands take no tokens": {
+			{"build", alice, 0, "ran build", general},
+			{"build", alice, 0, "Easy there! You reached the limit of commands for `build`. Try again in 1m0s", general},
+			{"hello", alice, 0, "ran hello", general},
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			now := time.Now()
+			l := &limiter{
+				limits:  limits,
+				buckets: map[string]*bucket{},
+				now:     func() time.Time { return now },
+			}
+			h := NewHandler()
+			h.Use(l.middleware)
+			reply := func(c *Command) error {
+				c.Message().Reply("ran "+c.Name(), false)
+				return nil
+			}
+			for _, name := range []string{"hello", "build"} {
+				if err := h.RegisterCommand(name, Spec{Verb: name}, reply); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for i, s := range tc {
+				now = now.Add(s.after)
+				msg := synthetic.NewMockMessage(s.text, true)
+				msg.SetUser(s.user)
+				msg.SetConversation(s.conversation)
+
+				h.Dispatch(NewCommand(msg))
+
+				if len(msg.Replies()) != 1 || msg.Replies()[0] != s.reply {
+					t.Errorf("wrong replies %v to message %d should be `%s`", msg.Replies(), i, s.reply)
+				}
+			}
+			if len(h.Failures()) != 0 {
+				t.Errorf("throttled commands counted as failures %v", h.Failures())
+			}
+		})
+	}
+}
+
+func TestRateLimitsValidate(t *testing.T) {
+	tt := map[string]struct {
+		limits RateLimits
+		valid  bool
+	}{
+		"Valid": {
+			limits: RateLimits{User: Rate{Burst: 5, Every: time.Minute}},
+			valid:  true,
+		},
+		"Unlimited": {
+			limits: RateLimits{},
+			valid:  true,
+		},
+		"No period": {
+			limits: RateLimits{Commands: map[string]Rate{"build": {Burst: 1}}},
+			valid:  false,
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			err := tc.limits.Validate()
+			if (err == nil) != tc.valid {
+				t.Errorf("wrong validation error %v for valid %v", err, tc.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+// jobsSpec is the Spec of the built-in jobs command.
+var jobsSpec = Spec{
+	Verb:      "jobs",
+	Summary:   "Lists the commands I'm running in this conversation or for you",
+	Category:  CategoryChat,
+	Unordered: true,
+}
+
+// cancelSpec is the Spec of the built-in cancel command.
+var cancelSpec = Spec{
+	Verb:      "cancel",
+	Args:      []Arg{{Name: "id", Required: true}},
+	Summary:   "Cancels one of your commands I'm running, using the ID given by `jobs`",
+	Examples:  []string{"cancel 3"},
+	Category:  CategoryChat,
+	Unordered: true,
+}
+
+// register assigns an ID to `command` and records it as queued until
+// the returned function is called. The command can be cancelled
+// through `cancel` meanwhile.
+func (c *Handler) register(command *Command, cancel context.CancelFunc) (unregister func()) {
+	c.registryMutex.Lock()
+	defer c.registryMutex.Unlock()
+	c.lastID++
+	id := c.lastID
+
+	command.status.Lock()
+	command.status.id = id
+	command.status.state = Queued
+	command.status.started = time.Now()
+	command.status.cancel = cancel
+	command.status.Unlock()
+
+	c.registry[id] = command
+	return func() {
+		c.registryMutex.Lock()
+		defer c.registryMutex.Unlock()
+		delete(c.registry, id)
+	}
+}
+
+// Running returns the commands currently queued or running, sorted by
+// ID.
+func (c *Handler) Running() []*Command {
+	c.registryMutex.Lock()
+	defer c.registryMutex.Unlock()
+	commands := []*Command{}
+	for _, command := range c.registry {
+		commands = append(commands, command)
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].ID() < commands[j].ID()
+	})
+	return commands
+}
+
+// running returns the command being run with `id`.
+func (c *Handler) running(id int) (*Command, error) {
+	c.registryMutex.Lock()
+	defer c.registryMutex.Unlock()
+	command, ok := c.registry[id]
+	if !ok {
+		return nil, fmt.Errorf("I'm not running any command with ID `%d`. Use `jobs` to get the list of commands I'm running", id)
+	}
+	return command, nil
+}
+
+// Cancel cancels the context of the command with `id`.
+func (c *Handler) Cancel(id int) (*Command, error) {
+	command, err := c.running(id)
+	if err != nil {
+		return nil, err
+	}
+
+	command.status.Lock()
+	command.status.state = Cancelling
+	cancel := command.status.cancel
+	command.status.Unlock()
+	cancel()
+	return command, nil
+}
+
+// jobs replies with the list of commands being run, but itself, in
+// the conversation of `command` or sent by its user, so the commands
+// in other conversations, like private ones, aren't disclosed.
+func (c *Handler) jobs(command *Command) error {
+	msg := command.Message()
+	result := ""
+	for _, running := range c.Running() {
+		if running.ID() == command.ID() {
+			continue
+		}
+		if running.Message().Conversation().ID() != msg.Conversation().ID() && running.Message().User().ID() != msg.User().ID() {
+			continue
+		}
+		result = fmt.Sprintf(
+			"%s- `#%d` `%s` by %s in %s: %s for %s",
+			result,
+			running.ID(),
+			running.Message().Text(),
+			running.Message().User().Name(),
+			running.Message().Conversation().Name(),
+			running.State(),
+			time.Since(running.Started()).Round(time.Second),
+		)
+		if url := running.TrackingURL(); url != "" {
+			result = fmt.Sprintf("%s (%s)", result, url)
+		}
+		result = fmt.Sprintf("%s\n", result)
+	}
+	if result == "" {
+		msg.Reply("I'm not running any command here or for you", msg.Thread())
+		return nil
+	}
+	msg.Reply(fmt.Sprintf("I'm running the following commands here or for you:\n%s", result), msg.Thread())
+	return nil
+}
+
+// cancel cancels the command with the ID in the `id` argument, as
+// long as it was sent by the same user, or by the system user, like
+// the scheduled commands.
+func (c *Handler) cancel(command *Command) error {
+	msg := command.Message()
+	id, err := strconv.Atoi(strings.TrimPrefix(command.Arg("id"), "#"))
+	if err != nil {
+		return fmt.Errorf("`%s` is not a valid command ID. Use `jobs` to get the list of commands I'm running", command.Arg("id"))
+	}
+	cancelled, err := c.running(id)
+	if err != nil {
+		return err
+	}
+	owner := cancelled.Message().User()
+	if owner.ID() != msg.User().ID() && owner.ID() != (synthetic.SystemUser{}).ID() {
+		return &DeniedError{
+			User:    msg.User().Name(),
+			Command: msg.Text(),
+			Reason:  fmt.Sprintf("as `#%d` was sent by %s", id, owner.Name()),
+		}
+	}
+	if _, err := c.Cancel(id); err != nil {
+		return err
+	}
+	msg.Reply(fmt.Sprintf("Cancelling `#%d` `%s`", id, cancelled.Message().Text()), msg.Thread())
+	return nil
+}
@@ -0,0 +1,236 @@
+package command
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+// blockingHandler returns a Handler with a `build` command blocking
+// until its context is done, and the channel where it signals the
+// command started.
+func blockingHandler(t *testing.T, maxConcurrent int) (*Handler, chan struct{}) {
+	h := NewHandler()
+	started := make(chan struct{}, 2)
+	err := h.RegisterCommand(
+		"build",
+		Spec{Verb: "build", Args: []Arg{{Name: "job"}}, MaxConcurrent: maxConcurrent},
+		func(c *Command) error {
+			c.Track("https://jenkins.example.com/job/" + c.Arg("job"))
+			started <- struct{}{}
+			<-c.Context().Done()
+			return c.Context().Err()
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h, started
+}
+
+// startLoop runs the EventLoop of `h` reading from the returned
+// channel, and returns the function stopping it.
+func startLoop(h *Handler) (chan synthetic.Message, func()) {
+	messages := make(chan synthetic.Message)
+	done := make(chan struct{})
+	go func() {
+		h.EventLoop(messages)
+		close(done)
+	}()
+	return messages, func() {
+		close(messages)
+		<-done
+	}
+}
+
+// conversationMessage returns a message like `userMessage` sent in the
+// conversation `conversation`.
+func conversationMessage(text, thread, user, conversation string) *synthetic.MockMessage {
+	msg := userMessage(text, thread, user).(*synthetic.MockMessage)
+	msg.SetConversation(synthetic.NewMockConversation(conversation, "#"+conversation))
+	return msg
+}
+
+func TestJobs(t *testing.T) {
+	disableLogs()
+	h, started := blockingHandler(t, 0)
+	messages, stop := startLoop(h)
+	defer stop()
+
+	empty := conversationMessage("jobs", "C1/0", "@alice", "C1")
+	messages <- empty
+	waitFor(t, func() bool { return len(h.Running()) == 0 && len(empty.Replies()) == 1 })
+	if reply := empty.Replies()[0]; reply != "I'm not running any command here or for you" {
+		t.Errorf("wrong reply `%s`", reply)
+	}
+
+	messages <- conversationMessage("build deploy", "C1/1", "@alice", "C1")
+	<-started
+	messages <- conversationMessage("build secret", "D2/1", "@bob", "D2")
+	<-started
+
+	tt := map[string]struct {
+		user         string
+		conversation string
+		listed       []string
+		hidden       []string
+	}{
+		"Same conversation": {
+			user:         "@carol",
+			conversation: "C1",
+			listed:       []string{"- `#2` `build deploy` by @alice in #C1: running for 0s (https://jenkins.example.com/job/deploy)\n"},
+			hidden:       []string{"secret", "`jobs`"},
+		},
+		"Own commands": {
+			user:         "@bob",
+			conversation: "C1",
+			listed:       []string{"`build deploy`", "- `#3` `build secret` by @bob in #D2"},
+		},
+		"Other conversation": {
+			user:         "@carol",
+			conversation: "C3",
+			listed:       []string{"I'm not running any command here or for you"},
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			jobs := conversationMessage("jobs", tc.conversation+"/"+testID, tc.user, tc.conversation)
+			messages <- jobs
+			waitFor(t, func() bool { return len(jobs.Replies()) == 1 })
+			reply := jobs.Replies()[0]
+			for _, expected := range tc.listed {
+				if !strings.Contains(reply, expected) {
+					t.Errorf("`%s` not found in `%s`", expected, reply)
+				}
+			}
+			for _, unexpected := range tc.hidden {
+				if strings.Contains(reply, unexpected) {
+					t.Errorf("`%s` shouldn't be listed in `%s`", unexpected, reply)
+				}
+			}
+		})
+	}
+
+	for _, command := range h.Running() {
+		if _, err := h.Cancel(command.ID()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// userMessage returns a message like `message` sent by `user`.
+func userMessage(text, thread, user string) synthetic.Message {
+	msg := message(text, thread).(*synthetic.MockMessage)
+	msg.SetUser(synthetic.NewMockUser("U"+user[1:], user))
+	return msg
+}
+
+func TestCancel(t *testing.T) {
+	disableLogs()
+	tt := map[string]struct {
+		maxConcurrent int
+		scheduled     bool
+		user          string
+		text          string
+		thread        string
+		replies       []string
+		cancelled     []string
+	}{
+		"Running command": {
+			text:      "cancel 1",
+			replies:   []string{"Cancelling `#1` `build deploy`"},
+			cancelled: []string{"context canceled"},
+		},
+		"Running command in its thread": {
+			text:      "cancel 1",
+			thread:    "C1/1",
+			replies:   []string{"Cancelling `#1` `build deploy`"},
+			cancelled: []string{"context canceled"},
+		},
+		"Queued command": {
+			maxConcurrent: 1,
+			text:          "cancel #2",
+			replies:       []string{"Cancelling `#2` `build deploy`"},
+			cancelled:     []string{"`#2` was cancelled before it started: context canceled"},
+		},
+		"Unknown command": {
+			text:    "cancel 7",
+			replies: []string{"I'm not running any command with ID `7`. Use `jobs` to get the list of commands I'm running"},
+		},
+		"Wrong ID": {
+			text:    "cancel last",
+			replies: []string{"`last` is not a valid command ID. Use `jobs` to get the list of commands I'm running"},
+		},
+		"Scheduled command": {
+			scheduled: true,
+			user:      "@bob",
+			text:      "cancel 1",
+			replies:   []string{"Cancelling `#1` `build deploy`"},
+			cancelled: []string{"context canceled"},
+		},
+		"Someone else's command": {
+			user:    "@bob",
+			text:    "cancel 1",
+			replies: []string{"Sorry @bob, you're not allowed to run `cancel 1` as `#1` was sent by @alice"},
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			h, started := blockingHandler(t, tc.maxConcurrent)
+			messages, stop := startLoop(h)
+			defer stop()
+
+			first := userMessage("build deploy", "C1/1", "@alice")
+			if tc.scheduled {
+				first.(*synthetic.MockMessage).SetUser(synthetic.NewMockUser("system", "@system"))
+			}
+			messages <- first
+			<-started
+			builds := []synthetic.Message{first}
+			if tc.maxConcurrent > 0 {
+				queued := userMessage("build deploy", "C1/2", "@alice")
+				messages <- queued
+				builds = []synthetic.Message{queued}
+				waitFor(t, func() bool { return h.QueueDepth() == 1 })
+			}
+			user := tc.user
+			if user == "" {
+				user = "@alice"
+			}
+			thread := tc.thread
+			if thread == "" {
+				thread = "C1/3"
+			}
+			cancel := userMessage(tc.text, thread, user)
+			messages <- cancel
+			waitFor(t, func() bool { return len(cancel.(*synthetic.MockMessage).Replies()) == len(tc.replies) })
+			for i, reply := range tc.replies {
+				if cancel.(*synthetic.MockMessage).Replies()[i] != reply {
+					t.Errorf("wrong reply `%s` should be `%s`", cancel.(*synthetic.MockMessage).Replies()[i], reply)
+				}
+			}
+
+			if len(tc.cancelled) > 0 {
+				build := builds[0].(*synthetic.MockMessage)
+				waitFor(t, func() bool { return len(build.Replies()) == len(tc.cancelled) })
+				for i, reply := range tc.cancelled {
+					if build.Replies()[i] != reply {
+						t.Errorf("wrong reply `%s` should be `%s`", build.Replies()[i], reply)
+					}
+				}
+			} else if command, err := h.running(1); err != nil || command.State() == Cancelling {
+				t.Errorf("`#1` shouldn't be cancelled, but got %v", err)
+			}
+
+			ctx, cancelShutdown := context.WithTimeout(context.Background(), time.Millisecond)
+			defer cancelShutdown()
+			for _, command := range h.Running() {
+				h.Cancel(command.ID())
+			}
+			h.Shutdown(ctx)
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+// Routing is the strategy a Handler uses to pick the commands
+// handling a message mentioning the bot.
+type Routing int
+
+const (
+	// Broadcast runs every command whose grammar is followed by
+	// the message.
+	Broadcast Routing = iota
+	// FirstMatch runs only one command: the one with the highest
+	// priority among those whose grammar is followed by the
+	// message. Messages matching no command get a suggestion.
+	FirstMatch
+)
+
+// SetRouting changes the strategy used to pick the commands handling
+// each message. The default one is Broadcast.
+func (c *Handler) SetRouting(routing Routing) {
+	c.routing = routing
+}
+
+// routeFirst returns `command` bound to the highest priority command
+// it matches. More specific commands win among the ones with the
+// same priority, and then the ones registered earlier.
+func (c *Handler) routeFirst(command *Command) (*Command, error) {
+	matching := c.matching(command.Tokens(), command)
+	sort.SliceStable(matching, func(i, j int) bool {
+		if matching[i].spec.Priority != matching[j].spec.Priority {
+			return matching[i].spec.Priority > matching[j].spec.Priority
+		}
+		return len(matching[i].spec.Words()) > len(matching[j].spec.Words())
+	})
+
+	var err error
+	for _, r := range matching {
+		arguments, parseErr := r.spec.Parse(command.Tokens())
+		if parseErr != nil {
+			if err == nil {
+				err = parseErr
+			}
+			continue
+		}
+		return command.bind(r.name, r.spec, arguments), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, c.unknown(command.Tokens())
+}
+
+// Route returns the commands `msg` would run, bound to their
+// arguments, including all the ones chained in it, without running
+// them. It returns the first error found tokenizing or routing them,
+// as well as when `msg` doesn't mention the bot.
+func (c *Handler) Route(msg synthetic.Message) ([]*Command, error) {
+	if !msg.Mention() {
+		return nil, fmt.Errorf("`%s` isn't sent to me", msg.Text())
+	}
+	command := NewCommand(msg)
+	if command.tokenizeErr != nil {
+		return nil, command.tokenizeErr
+	}
+	steps, err := c.chain(command)
+	if err != nil {
+		return nil, err
+	}
+	routed := []*Command{}
+	for _, s := range steps {
+		step := command
+		if len(steps) > 1 {
+			step = NewCommand(&rewrittenMessage{Message: msg, text: s.text()})
+		}
+		bound, err := c.route(step)
+		if err != nil {
+			return nil, err
+		}
+		if len(bound) == 0 {
+			return nil, c.unknown(step.Tokens())
+		}
+		routed = append(routed, bound...)
+	}
+	return routed, nil
+}
+
+// unknown returns the error for `tokens` matching no command,
+// suggesting the closest one if any.
+func (c *Handler) unknown(tokens []string) error {
+	if len(tokens) == 0 {
+		return fmt.Errorf("use `help` to get the list of commands I know")
+	}
+
+	// On ties, the suggestion with more words is the one that
+	// better matches the tokens.
+	suggestion := ""
+	best := -1
+	for _, r := range c.commands {
+		words := r.spec.Words()
+		n := len(words)
+		if n > len(tokens) {
+			n = len(tokens)
+		}
+		d := distance(strings.Join(tokens[:n], " "), strings.Join(words, " "))
+		if best < 0 || d < best || (d == best && len(words) > len(strings.Fields(suggestion))) {
+			best = d
+			suggestion = strings.Join(words, " ")
+		}
+	}
+
+	if best < 0 || best > len(suggestion)/2 {
+		return fmt.Errorf("I don't know how to `%s`. Use `help` to get the list of commands I know", tokens[0])
+	}
+	return fmt.Errorf("I don't know how to `%s`. Did you mean `%s`?", tokens[0], suggestion)
+}
+
+// distance returns the Levenshtein distance between `a` and `b`.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current := make([]int, len(rb)+1)
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(rb)]
+}
+
+func min(values ...int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+	return result
+}
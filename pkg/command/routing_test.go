@@ -0,0 +1,135 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+func TestFirstMatch(t *testing.T) {
+	tt := map[string]struct {
+		text    string
+		runs    []string
+		replies []string
+	}{
+		"Most specific command": {
+			text: "list pods cluster1",
+			runs: []string{"listener", "list pods"},
+		},
+		"Highest priority": {
+			text: "build deploy",
+			runs: []string{"listener", "deploy"},
+		},
+		"Match function": {
+			text: "build test",
+			runs: []string{"listener", "build"},
+		},
+		"Usage error": {
+			text:    "list nodes",
+			runs:    []string{"listener"},
+			replies: []string{"unexpected argument `nodes`. Usage: `list`"},
+		},
+		"Did you mean": {
+			text:    "lsit pods",
+			runs:    []string{"listener"},
+			replies: []string{"I don't know how to `lsit`. Did you mean `list pods`?"},
+		},
+		"Unknown command": {
+			text:    "sing a song",
+			runs:    []string{"listener"},
+			replies: []string{"I don't know how to `sing`. Use `help` to get the list of commands I know"},
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			r := newRecorder()
+			h := testHandler(t, r)
+			h.SetRouting(FirstMatch)
+			err := h.RegisterCommand(
+				"deploy",
+				Spec{
+					Verb:     "build",
+					Args:     []Arg{{Name: "job", Required: true}},
+					Match:    func(c *Command) bool { return c.Tokens()[1] == "deploy" },
+					Priority: 10,
+				},
+				r.executor("deploy"),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg := synthetic.NewMockMessage(tc.text, true)
+			h.Dispatch(NewCommand(msg))
+
+			if len(r.runs) != len(tc.runs) {
+				t.Errorf("wrong number of executors run %v should be %v", len(r.runs), len(tc.runs))
+			}
+			for _, name := range tc.runs {
+				if _, ok := r.runs[name]; !ok {
+					t.Errorf("executor `%s` wasn't run", name)
+				}
+			}
+			if len(msg.Replies()) != len(tc.replies) {
+				t.Fatalf("wrong number of replies %v should be %v", len(msg.Replies()), len(tc.replies))
+			}
+			for i, reply := range tc.replies {
+				if msg.Replies()[i] != reply {
+					t.Errorf("wrong reply `%s` should be `%s`", msg.Replies()[i], reply)
+				}
+			}
+		})
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tt := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"list", "list", 0},
+		{"lsit", "list", 2},
+		{"buidl", "build", 2},
+		{"describe", "desc", 4},
+	}
+	for _, tc := range tt {
+		if d := distance(tc.a, tc.b); d != tc.distance {
+			t.Errorf("wrong distance between `%s` and `%s` %v should be %v", tc.a, tc.b, d, tc.distance)
+		}
+	}
+}
+
+func TestRoute(t *testing.T) {
+	tt := map[string]struct {
+		text     string
+		mention  bool
+		commands []string
+		err      bool
+	}{
+		"Command":          {text: "list pods cluster1", mention: true, commands: []string{"list pods"}},
+		"Chain":            {text: "build a && list clusters; list", mention: true, commands: []string{"build", "list clusters", "list"}},
+		"Unknown command":  {text: "build a && sing a song", mention: true, err: true},
+		"Usage error":      {text: "list nodes", mention: true, err: true},
+		"Wrong chain":      {text: "build a &&", mention: true, err: true},
+		"Unbalanced quote": {text: "build \"a", mention: true, err: true},
+		"Not mentioned":    {text: "list", err: true},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			h := testHandler(t, newRecorder())
+			h.SetRouting(FirstMatch)
+			commands, err := h.Route(synthetic.NewMockMessage(tc.text, tc.mention))
+			if (err != nil) != tc.err {
+				t.Fatalf("wrong error %v", err)
+			}
+			names := []string{}
+			for _, c := range commands {
+				names = append(names, c.Name())
+			}
+			if strings.Join(names, ",") != strings.Join(tc.commands, ",") {
+				t.Errorf("wrong commands %v should be %v", names, tc.commands)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultSessionTimeout is how long a Session waits for each answer,
+// unless changed with SetSessionTimeout.
+const defaultSessionTimeout = 5 * time.Minute
+
+// ErrSessionCancelled is returned by Session.Ask when the user
+// answers `cancel`.
+var ErrSessionCancelled = errors.New("cancelled by the user")
+
+// Session is the conversation between a Command and the user who sent
+// it, in the thread of its message. It lets the Executor ask for
+// anything missing in the Command.
+type Session struct {
+	handler *Handler
+	command *Command
+}
+
+// SetSessionTimeout sets how long the Sessions wait for the users to
+// answer each question.
+func (c *Handler) SetSessionTimeout(timeout time.Duration) {
+	c.sessionTimeout = timeout
+}
+
+// Ask replies with `question`, and returns the next message of the
+// user in the thread. That message isn't handled as a command. It
+// fails if the user doesn't answer in time, answers `cancel`, or the
+// Command is cancelled, and right away if the message is unattended.
+func (s *Session) Ask(question string) (string, error) {
+	msg := s.command.Message()
+	if msg.Unattended() {
+		return "", fmt.Errorf("`%s` can't run unattended, as I'd have to ask: %s", msg.Text(), question)
+	}
+	msg.Reply(fmt.Sprintf("%s (answer `cancel` to stop)", question), msg.Thread())
+
+	ctx, cancel := withTimeout(s.command.Context(), s.handler.sessionTimeout)
+	defer cancel()
+	a, err := s.handler.await(ctx, s.command, Waiting, false)
+	if err != nil {
+		if s.command.Context().Err() != nil {
+			return "", s.command.Context().Err()
+		}
+		return "", fmt.Errorf("I didn't get an answer within %v, so I gave up on `%s`", s.handler.sessionTimeout, msg.Text())
+	}
+	text := strings.TrimSpace(a.message.Text())
+	if strings.EqualFold(text, "cancel") {
+		return "", ErrSessionCancelled
+	}
+	s.command.setState(Running)
+	return text, nil
+}
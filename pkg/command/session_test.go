@@ -0,0 +1,104 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+func TestSession(t *testing.T) {
+	disableLogs()
+	alice := synthetic.NewMockUser("U000001", "@alice")
+	bob := synthetic.NewMockUser("U000002", "@bob")
+	type answer struct {
+		text string
+		user synthetic.MockUser
+	}
+	tt := map[string]struct {
+		answers    []answer
+		unattended bool
+		replies    []string
+	}{
+		"Answered": {
+			answers: []answer{{"staging", alice}, {"users", alice}},
+			replies: []string{
+				"Which environment? (answer `cancel` to stop)",
+				"Which index? (answer `cancel` to stop)",
+				"Deploying users to staging",
+			},
+		},
+		"Other users ignored": {
+			answers: []answer{{"production", bob}, {"staging", alice}, {"users", alice}},
+			replies: []string{
+				"Which environment? (answer `cancel` to stop)",
+				"Which index? (answer `cancel` to stop)",
+				"Deploying users to staging",
+			},
+		},
+		"Cancelled": {
+			answers: []answer{{"Cancel", alice}},
+			replies: []string{
+				"Which environment? (answer `cancel` to stop)",
+				"OK, I cancelled `deploy`",
+			},
+		},
+		"Unattended": {
+			unattended: true,
+			replies:    []string{"`deploy` can't run unattended, as I'd have to ask: Which environment?"},
+		},
+		"Not answered": {
+			replies: []string{
+				"Which environment? (answer `cancel` to stop)",
+				"I didn't get an answer within 100ms, so I gave up on `deploy`",
+			},
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			h := NewHandler()
+			h.SetSessionTimeout(100 * time.Millisecond)
+			err := h.RegisterCommand("deploy", Spec{Verb: "deploy"}, func(c *Command) error {
+				environment, err := c.Session().Ask("Which environment?")
+				if err != nil {
+					return err
+				}
+				index, err := c.Session().Ask("Which index?")
+				if err != nil {
+					return err
+				}
+				c.Message().Reply("Deploying "+index+" to "+environment, false)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			messages, stop := startLoop(h)
+			defer stop()
+
+			msg := synthetic.NewMockMessage("deploy", true)
+			msg.SetThreadID("C1/1")
+			msg.SetUser(alice)
+			msg.SetUnattended(tc.unattended)
+			messages <- msg
+			for _, a := range tc.answers {
+				waitFor(t, func() bool { return len(h.Running()) == 1 && h.Running()[0].State() == Waiting })
+				replies := len(msg.Replies())
+				answer := synthetic.NewMockMessage(a.text, false)
+				answer.SetThreadID("C1/1")
+				answer.SetUser(a.user)
+				messages <- answer
+				if a.user.ID() == alice.ID() {
+					waitFor(t, func() bool { return len(msg.Replies()) > replies })
+				}
+			}
+			waitFor(t, func() bool { return len(msg.Replies()) == len(tc.replies) && len(h.Running()) == 0 })
+
+			for i, reply := range tc.replies {
+				if msg.Replies()[i] != reply {
+					t.Errorf("wrong reply `%s` should be `%s`", msg.Replies()[i], reply)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// Shutdown stops EventLoop from taking new messages and waits for the
+// ones already taken to be handled. If `ctx` is done before that, it
+// lets the users know about the commands still running, pointing
+// them to where they can follow them, and returns the context error.
+func (c *Handler) Shutdown(ctx context.Context) error {
+	c.threadsMutex.Lock()
+	if !c.stopped {
+		c.stopped = true
+		close(c.stopping)
+	}
+	c.threadsMutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		for _, command := range c.Running() {
+			log.Printf("Shutting down while %v is running", command.Name())
+			msg := command.Message()
+			if url := command.TrackingURL(); url != "" {
+				msg.Reply(fmt.Sprintf("Bot restarting, follow the build at %s", url), true)
+				continue
+			}
+			msg.Reply(fmt.Sprintf("Bot restarting, I couldn't complete `%s`", command.Name()), true)
+		}
+		return ctx.Err()
+	}
+}
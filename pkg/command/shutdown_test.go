@@ -0,0 +1,117 @@
+package command
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+func TestShutdown(t *testing.T) {
+	disableLogs()
+	tt := map[string]struct {
+		url     string
+		timeout time.Duration
+		err     error
+		replies []string
+	}{
+		"Commands complete": {
+			timeout: time.Second,
+			err:     nil,
+			replies: []string{},
+		},
+		"Deadline with tracking URL": {
+			url:     "https://jenkins.example.com/job/deploy/1/",
+			timeout: 10 * time.Millisecond,
+			err:     context.DeadlineExceeded,
+			replies: []string{"Bot restarting, follow the build at https://jenkins.example.com/job/deploy/1/"},
+		},
+		"Deadline without tracking URL": {
+			timeout: 10 * time.Millisecond,
+			err:     context.DeadlineExceeded,
+			replies: []string{"Bot restarting, I couldn't complete `build`"},
+		},
+	}
+	for testID, tc := range tt {
+		tc := tc
+		t.Run(testID, func(t *testing.T) {
+			h := NewHandler()
+			running := make(chan struct{})
+			release := make(chan struct{})
+			err := h.RegisterCommand("build", Spec{Verb: "build"}, func(c *Command) error {
+				if tc.url != "" {
+					c.Track(tc.url)
+				}
+				close(running)
+				select {
+				case <-release:
+				case <-time.After(100 * time.Millisecond):
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			messages := make(chan synthetic.Message, 1)
+			done := make(chan struct{})
+			go func() {
+				h.EventLoop(messages)
+				close(done)
+			}()
+			msg := synthetic.NewMockMessage("build", true)
+			messages <- msg
+			<-running
+
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+			go func() {
+				time.Sleep(tc.timeout / 2)
+				if tc.err == nil {
+					close(release)
+				}
+			}()
+			err = h.Shutdown(ctx)
+
+			if err != tc.err {
+				t.Errorf("wrong error %v should be %v", err, tc.err)
+			}
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("EventLoop should return on Shutdown")
+			}
+			if len(msg.Replies()) != len(tc.replies) {
+				t.Fatalf("wrong replies %v should be %v", msg.Replies(), tc.replies)
+			}
+			for i, reply := range tc.replies {
+				if msg.Replies()[i] != reply {
+					t.Errorf("wrong reply `%s` should be `%s`", msg.Replies()[i], reply)
+				}
+			}
+		})
+	}
+}
+
+func TestShutdownIgnoresNewMessages(t *testing.T) {
+	disableLogs()
+	h := NewHandler()
+	ran := false
+	err := h.RegisterCommand("build", Spec{Verb: "build"}, func(c *Command) error {
+		ran = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	h.enqueue(NewCommand(synthetic.NewMockMessage("build", true)))
+	h.inFlight.Wait()
+
+	if ran {
+		t.Error("messages shouldn't be handled after Shutdown")
+	}
+}
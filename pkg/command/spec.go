@@ -0,0 +1,214 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+// Arg describes a positional argument of a command.
+type Arg struct {
+	Name     string
+	Required bool
+	// Variadic arguments take all the remaining positional tokens,
+	// so they can only be the last argument of a Spec.
+	Variadic bool
+}
+
+// Spec declares the grammar a message must follow to run a command:
+// a verb, optionally followed by some subcommands, then positional
+// arguments, `key=value` parameters and `--flag` flags in any order.
+type Spec struct {
+	Verb        string
+	Subcommands []string
+	Args        []Arg
+	// Params lists the accepted `key=value` parameters. Any
+	// parameter is accepted when AnyParams is true.
+	Params    []string
+	AnyParams bool
+	Flags     []string
+
+	// Match, when set, must also accept a Command for it to match
+	// the Spec. It allows conditions the grammar can't express.
+	Match func(*Command) bool
+	// Priority decides which command handles a message when
+	// several of them match it using the FirstMatch routing. The
+	// highest priority wins.
+	Priority int
+	// Timeout limits how long the command can run. The Handler
+	// default timeout is used when it's zero.
+	Timeout time.Duration
+	// MaxConcurrent limits how many instances of the command can
+	// run at the same time. Zero means no limit.
+	MaxConcurrent int
+	// Confirm requires the user to confirm the command before it
+	// runs, after the bot replies with a summary of it. ConfirmIf,
+	// when set, limits it to the commands it accepts.
+	Confirm   bool
+	ConfirmIf func(*Command) bool
+	// Overflow is how the replies of the command too long for the
+	// chat platform are sent. The platform default is used when
+	// it's empty.
+	Overflow synthetic.Overflow
+	// PublicErrors replies the errors of the command to everyone in
+	// the conversation, like the failures of builds. Otherwise, only
+	// the user who sent the command sees them.
+	PublicErrors bool
+	// Unordered commands run as soon as they're received, instead
+	// of after the commands sent before them in the same thread,
+	// like `cancel` for a build running in the thread.
+	Unordered bool
+	// Unchained commands take the chain operators after them, like
+	// `&&`, as arguments instead of chaining other commands, like
+	// `schedule` does with the command to schedule.
+	Unchained bool
+
+	// Summary, Usage, Examples and Category document the command
+	// in the help. Usage is generated from the grammar when empty.
+	Summary  string
+	Usage    string
+	Examples []string
+	Category string
+}
+
+// Arguments holds the values parsed from a Command following a Spec.
+type Arguments struct {
+	Args   map[string]string
+	Params map[string]string
+	Flags  map[string]bool
+}
+
+// UsageError is returned when a Command matches a Spec verb and
+// subcommands but not the rest of the grammar.
+type UsageError struct {
+	Spec   *Spec
+	Reason string
+}
+
+func (e *UsageError) Error() string {
+	return fmt.Sprintf("%s. Usage: `%s`", e.Reason, e.Spec.Synopsis())
+}
+
+// Words returns the verb and subcommands of the Spec.
+func (s *Spec) Words() []string {
+	return append([]string{s.Verb}, s.Subcommands...)
+}
+
+// Synopsis returns a one line description of the Spec grammar.
+func (s *Spec) Synopsis() string {
+	if s.Usage != "" {
+		return s.Usage
+	}
+	usage := s.Words()
+	for _, arg := range s.Args {
+		name := fmt.Sprintf("<%s>", arg.Name)
+		if arg.Variadic {
+			name = fmt.Sprintf("%s...", name)
+		}
+		if !arg.Required {
+			name = fmt.Sprintf("[%s]", name)
+		}
+		usage = append(usage, name)
+	}
+	for _, param := range s.Params {
+		usage = append(usage, fmt.Sprintf("[%s=<value>]", param))
+	}
+	if s.AnyParams {
+		usage = append(usage, "[KEY=VALUE...]")
+	}
+	for _, flag := range s.Flags {
+		usage = append(usage, fmt.Sprintf("[--%s]", flag))
+	}
+	return strings.Join(usage, " ")
+}
+
+// Matches reports whether `tokens` start with the verb and
+// subcommands of the Spec.
+func (s *Spec) Matches(tokens []string) bool {
+	return startsWith(tokens, s.Words())
+}
+
+// Parse returns the Arguments in `tokens` according to the Spec. It
+// returns a *UsageError when `tokens` don't follow the grammar.
+func (s *Spec) Parse(tokens []string) (*Arguments, error) {
+	if !s.Matches(tokens) {
+		return nil, &UsageError{s, "unknown command"}
+	}
+	args := &Arguments{
+		Args:   map[string]string{},
+		Params: map[string]string{},
+		Flags:  map[string]bool{},
+	}
+
+	positional := []string{}
+	for _, token := range tokens[len(s.Words()):] {
+		switch {
+		case strings.HasPrefix(token, "--"):
+			flag := strings.TrimPrefix(token, "--")
+			if !Contains(s.Flags, flag) {
+				return nil, &UsageError{s, fmt.Sprintf("unknown flag `%s`", token)}
+			}
+			args.Flags[flag] = true
+		case isParam(token):
+			data := strings.SplitN(token, "=", 2)
+			if !s.AnyParams && !Contains(s.Params, data[0]) {
+				return nil, &UsageError{s, fmt.Sprintf("unknown parameter `%s`", data[0])}
+			}
+			args.Params[data[0]] = data[1]
+		default:
+			positional = append(positional, token)
+		}
+	}
+
+	for _, arg := range s.Args {
+		if len(positional) == 0 {
+			if arg.Required {
+				return nil, &UsageError{s, fmt.Sprintf("missing argument `%s`", arg.Name)}
+			}
+			continue
+		}
+		if arg.Variadic {
+			args.Args[arg.Name] = strings.Join(positional, " ")
+			positional = nil
+			continue
+		}
+		args.Args[arg.Name] = positional[0]
+		positional = positional[1:]
+	}
+	if len(positional) > 0 {
+		return nil, &UsageError{s, fmt.Sprintf("unexpected argument `%s`", positional[0])}
+	}
+
+	return args, nil
+}
+
+// isParam reports whether `token` has the `key=value` form.
+func isParam(token string) bool {
+	i := strings.Index(token, "=")
+	return i > 0
+}
+
+// startsWith reports whether `prefix` is a prefix of `words`.
+func startsWith(words, prefix []string) bool {
+	if len(prefix) > len(words) {
+		return false
+	}
+	for i, word := range prefix {
+		if words[i] != word {
+			return false
+		}
+	}
+	return true
+}
+
+// Contains reports whether `items` contains `item`.
+func Contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package command
+
+import (
+	"testing"
+)
+
+func TestSpecSynopsis(t *testing.T) {
+	tt := map[string]struct {
+		spec  Spec
+		usage string
+	}{
+		"Verb only": {
+			spec:  Spec{Verb: "list"},
+			usage: "list",
+		},
+		"Subcommands and optional arguments": {
+			spec: Spec{
+				Verb:        "list",
+				Subcommands: []string{"pods"},
+				Args:        []Arg{{Name: "cluster"}, {Name: "namespace"}},
+			},
+			usage: "list pods [<cluster>] [<namespace>]",
+		},
+		"Everything": {
+			spec: Spec{
+				Verb:      "build",
+				Args:      []Arg{{Name: "job", Required: true}, {Name: "rest", Variadic: true}},
+				Params:    []string{"ENV"},
+				AnyParams: true,
+				Flags:     []string{"force"},
+			},
+			usage: "build <job> [<rest>...] [ENV=<value>] [KEY=VALUE...] [--force]",
+		},
+		"Explicit usage": {
+			spec:  Spec{Verb: "build", AnyParams: true, Usage: "build <job> [PARAM=VALUE...]"},
+			usage: "build <job> [PARAM=VALUE...]",
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			if usage := tc.spec.Synopsis(); usage != tc.usage {
+				t.Errorf("wrong usage `%s` should be `%s`", usage, tc.usage)
+			}
+		})
+	}
+}
+
+func TestSpecParse(t *testing.T) {
+	spec := Spec{
+		Verb:   "build",
+		Args:   []Arg{{Name: "job", Required: true}, {Name: "target"}},
+		Params: []string{"ENV"},
+		Flags:  []string{"force"},
+	}
+	tt := map[string]struct {
+		tokens []string
+		args   map[string]string
+		params map[string]string
+		flags  map[string]bool
+		err    string
+	}{
+		"Required argument": {
+			tokens: []string{"build", "deploy"},
+			args:   map[string]string{"job": "deploy"},
+		},
+		"Arguments, parameters and flags": {
+			tokens: []string{"build", "--force", "deploy", "ENV=prod", "api"},
+			args:   map[string]string{"job": "deploy", "target": "api"},
+			params: map[string]string{"ENV": "prod"},
+			flags:  map[string]bool{"force": true},
+		},
+		"Parameter value with equals": {
+			tokens: []string{"build", "deploy", "ENV=a=b"},
+			args:   map[string]string{"job": "deploy"},
+			params: map[string]string{"ENV": "a=b"},
+		},
+		"Wrong verb": {
+			tokens: []string{"rebuild", "deploy"},
+			err:    "unknown command. Usage: `build <job> [<target>] [ENV=<value>] [--force]`",
+		},
+		"Missing argument": {
+			tokens: []string{"build"},
+			err:    "missing argument `job`. Usage: `build <job> [<target>] [ENV=<value>] [--force]`",
+		},
+		"Unexpected argument": {
+			tokens: []string{"build", "deploy", "api", "web"},
+			err:    "unexpected argument `web`. Usage: `build <job> [<target>] [ENV=<value>] [--force]`",
+		},
+		"Unknown parameter": {
+			tokens: []string{"build", "deploy", "INDEX=users"},
+			err:    "unknown parameter `INDEX`. Usage: `build <job> [<target>] [ENV=<value>] [--force]`",
+		},
+		"Unknown flag": {
+			tokens: []string{"build", "deploy", "--dry-run"},
+			err:    "unknown flag `--dry-run`. Usage: `build <job> [<target>] [ENV=<value>] [--force]`",
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			arguments, err := spec.Parse(tc.tokens)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("expected error `%v` but got `%v`", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			for name, value := range tc.args {
+				if arguments.Args[name] != value {
+					t.Errorf("wrong argument %s `%s` should be `%s`", name, arguments.Args[name], value)
+				}
+			}
+			for name, value := range tc.params {
+				if arguments.Params[name] != value {
+					t.Errorf("wrong parameter %s `%s` should be `%s`", name, arguments.Params[name], value)
+				}
+			}
+			for name, value := range tc.flags {
+				if arguments.Flags[name] != value {
+					t.Errorf("wrong flag %s `%v` should be `%v`", name, arguments.Flags[name], value)
+				}
+			}
+		})
+	}
+}
+
+func TestSpecVariadic(t *testing.T) {
+	spec := Spec{Verb: "hello", Args: []Arg{{Name: "greeting", Variadic: true}}}
+	arguments, err := spec.Parse([]string{"hello", "there", "bot"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if arguments.Args["greeting"] != "there bot" {
+		t.Errorf("wrong variadic argument `%s`", arguments.Args["greeting"])
+	}
+}
@@ -0,0 +1,26 @@
+// Package environ builds the environment of the processes run by the
+// bot, like plugins and scripts, so they don't get its credentials.
+package environ
+
+import "os"
+
+// defaults are the variables always given to the processes.
+var defaults = []string{"PATH", "HOME"}
+
+// Allowed returns the variables of the bot environment named in
+// `names`, and the defaults, as `name=value` strings for exec.Cmd.Env.
+// The rest of variables, like SLACK_TOKEN, are left out.
+func Allowed(names []string) []string {
+	env := []string{}
+	seen := map[string]bool{}
+	for _, name := range append(append([]string{}, defaults...), names...) {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
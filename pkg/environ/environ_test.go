@@ -0,0 +1,50 @@
+package environ
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+// setenv sets the environment variable `name` to `value` until the
+// test ends.
+func setenv(t *testing.T, name, value string) {
+	previous, ok := os.LookupEnv(name)
+	if err := os.Setenv(name, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(name, previous)
+		} else {
+			os.Unsetenv(name)
+		}
+	})
+}
+
+func TestAllowed(t *testing.T) {
+	setenv(t, "PATH", "/usr/bin:/bin")
+	setenv(t, "HOME", "/home/synthetic")
+	setenv(t, "SLACK_TOKEN", "xoxb-secret")
+	setenv(t, "KUBECONFIG", "/etc/kubeconfig")
+
+	tt := map[string]struct {
+		names    []string
+		expected []string
+	}{
+		"Defaults": {
+			expected: []string{"PATH=/usr/bin:/bin", "HOME=/home/synthetic"},
+		},
+		"Named": {
+			names:    []string{"KUBECONFIG", "PATH", "MISSING"},
+			expected: []string{"PATH=/usr/bin:/bin", "HOME=/home/synthetic", "KUBECONFIG=/etc/kubeconfig"},
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			if env := Allowed(tc.names); !reflect.DeepEqual(env, tc.expected) {
+				t.Errorf("wrong environment %v should be %v", env, tc.expected)
+			}
+		})
+	}
+}
@@ -1,12 +1,29 @@
 package jobcontrol
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"strings"
 
 	"github.com/ifosch/synthetic/pkg/synthetic"
+	"github.com/ifosch/synthetic/pkg/tokenizer"
 )
 
+// Tracker is told the URL of the builds, so it can point users to
+// them, and the parameters they run with, including the ones asked
+// for, so it can audit them.
+type Tracker interface {
+	Track(url string)
+	TrackParams(params map[string]string)
+}
+
+// Asker asks the users for the information missing in their
+// commands, and returns their answers.
+type Asker interface {
+	Ask(question string) (string, error)
+}
+
 // Jenkins is the object to handle the Jenkins connection.
 type Jenkins struct {
 	url, user, password string
@@ -22,9 +39,8 @@ func NewJenkins(url, user, password string) *Jenkins {
 
 // Connect to the jenkins server with the credentials used during
 // initialization
-func (j *Jenkins) Connect() error {
-	j.js.Connect(j.url, j.user, j.password)
-	return nil
+func (j *Jenkins) Connect(ctx context.Context) error {
+	return j.js.Connect(ctx, j.url, j.user, j.password)
 }
 
 // ParseArgs provides parameters and options parsing from a message.
@@ -32,11 +48,14 @@ func (j *Jenkins) ParseArgs(input, command string) (job string, args map[string]
 	args = make(map[string]string)
 
 	var options []string
-	tokens := tokenizeParams(input)
+	tokens, err := tokenizer.Split(input)
+	if err != nil {
+		return "", nil, err
+	}
 	for _, token := range tokens {
 		if token != command {
 			if strings.Contains(token, "=") {
-				data := strings.Split(token, "=")
+				data := strings.SplitN(token, "=", 2)
 				args[data[0]] = data[1]
 			} else {
 				options = append(options, token)
@@ -57,61 +76,129 @@ func (j *Jenkins) ParseArgs(input, command string) (job string, args map[string]
 	return job, args, nil
 }
 
-// Reload runs Load again.
-func (j *Jenkins) Reload(msg synthetic.Message) {
+// Reload runs Load again. The jobs loaded before are kept until the
+// new ones are loaded, so the commands running meanwhile find them.
+func (j *Jenkins) Reload(ctx context.Context, msg synthetic.Message) error {
 	msg.React("+1")
-	j.js.GetJobs().Clear()
-	err := j.js.Load()
+	err := j.js.Load(ctx)
 	if err != nil {
-		msg.Reply(fmt.Sprintf("Error happened reloading jobs %s", err), msg.Thread())
-		return
+		return fmt.Errorf("error happened reloading jobs %s", err)
 	}
 
 	msg.Reply(fmt.Sprintf("%v Jenkins jobs reloaded", j.js.GetJobs().Len()), msg.Thread())
 	msg.React("heavy_check_mark")
+	return nil
 }
 
 // Describe replies `msg` with the description of a job defined.
-func (j *Jenkins) Describe(msg synthetic.Message) {
+func (j *Jenkins) Describe(msg synthetic.Message) error {
 	job, _, err := j.ParseArgs(msg.Text(), "describe")
 	if err != nil {
-		msg.Reply(fmt.Sprintf("%s", err), msg.Thread())
-		return
+		return err
 	}
 	msg.Reply(j.js.GetJob(job).Describe(), msg.Thread())
+	return nil
 }
 
-// List replies `msg` with the list of jobs in the Jenkins instance.
+// List replies `msg` with the list of jobs in the Jenkins instance,
+// with the first line of their descriptions.
 func (j *Jenkins) List(msg synthetic.Message) {
-	msg.Reply(j.js.GetJobs().List(), msg.Thread())
+	jobs := j.js.GetJobs().Jobs()
+	if len(jobs) == 0 {
+		msg.Reply("I know of no jobs. If there are new ones, try using `reload` to refresh the list of jobs", msg.Thread())
+		return
+	}
+	table := &synthetic.Table{Columns: []string{"Job", "Description"}}
+	for _, job := range jobs {
+		description := strings.SplitN(trim(job.Description()), "\n", 2)[0]
+		table.Rows = append(table.Rows, []string{job.Name(), description})
+	}
+	msg.ReplyResponse(&synthetic.Response{
+		Header: "Jenkins jobs",
+		Blocks: []synthetic.Block{
+			table,
+			synthetic.Context(fmt.Sprintf("%d jobs. Use `describe <job>` to get the details of a job", len(jobs))),
+		},
+	}, msg.Thread())
 }
 
-// Build runs specified job, with the specified options. It receives
-// the job processing updates from Jenkins and reacts and replies with
-// these to `msg`.
-func (j *Jenkins) Build(msg synthetic.Message) {
+// Build runs specified job, with the specified options. The job
+// parameters without a default value missing in `msg` are asked with
+// `asker`, if not nil. It receives the job processing updates from
+// Jenkins and reacts to `msg` with these, replying with the progress
+// in a single message edited in place, and with the result in the
+// thread. It stops following the job when `ctx` is done. The build
+// parameters are given to `tracker` before the job is run, and the
+// build URL once it starts building. It returns an error when the job
+// doesn't succeed, wrapping context.Canceled if it was cancelled.
+func (j *Jenkins) Build(ctx context.Context, msg synthetic.Message, tracker Tracker, asker Asker) error {
 	job, args, err := j.ParseArgs(msg.Text(), "build")
 	if err != nil {
-		msg.Reply(fmt.Sprintf("%s", err), msg.Thread())
-		return
+		return err
 	}
+	if asker != nil {
+		if err := askMissing(j.js.GetJob(job), args, asker); err != nil {
+			return err
+		}
+	}
+	tracker.TrackParams(args)
 
 	msg.React("+1")
 
 	updates := make(chan Update)
 	defer close(updates)
 
-	go j.js.GetJob(job).Run(args, updates)
+	go j.js.GetJob(job).Run(ctx, args, updates)
 
 	lastReaction := ""
+	var progress synthetic.Reply
 	for {
 		update := <-updates
 		msg.Unreact(lastReaction)
+		if update.Err != nil {
+			// The error is reported to the user by the caller.
+			return update.Err
+		}
 		msg.React(update.Reaction)
-		msg.Reply(update.Msg, msg.Thread())
+		switch {
+		case update.Done:
+			msg.Reply(update.Msg, true)
+		case progress == nil:
+			progress = msg.Reply(update.Msg, msg.Thread())
+		default:
+			if err := progress.Edit(update.Msg); err != nil {
+				log.Printf("Error editing the progress of `%v`: %v", job, err)
+				progress = msg.Reply(update.Msg, msg.Thread())
+			}
+		}
+		if update.URL != "" {
+			tracker.Track(update.URL)
+		}
 		lastReaction = update.Reaction
 		if update.Done {
 			break
 		}
 	}
+	return nil
+}
+
+// askMissing asks with `asker` for the parameters of `job` without a
+// default value missing in `args`, one after another, and adds the
+// answers to `args`.
+func askMissing(job IJob, args map[string]string, asker Asker) error {
+	for _, parameter := range job.Parameters() {
+		if _, ok := args[parameter.Name]; ok || parameter.HasDefault {
+			continue
+		}
+		question := fmt.Sprintf("What's the value of *%s* for `%s`?", parameter.Name, job.Name())
+		if parameter.Description != "" {
+			question = fmt.Sprintf("%s %s", question, parameter.Description)
+		}
+		value, err := asker.Ask(question)
+		if err != nil {
+			return err
+		}
+		args[parameter.Name] = value
+	}
+	return nil
 }
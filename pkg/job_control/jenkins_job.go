@@ -5,17 +5,29 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
 	"github.com/bndr/gojenkins"
 )
 
+// stopTimeout limits how long aborting a build on the server can
+// take, once the context of the build was cancelled.
+const stopTimeout = 10 * time.Second
+
+// describeTemplate is the template of Describe, parsed once by the
+// first Job described.
+var (
+	describeOnce     sync.Once
+	describeTemplate *template.Template
+	describeErr      error
+)
+
 // Job is an implementation of an IJob.
 type Job struct {
-	client           *gojenkins.Jenkins
-	jenkinsJob       *gojenkins.Job
-	describeTemplate *template.Template
+	client     *gojenkins.Jenkins
+	jenkinsJob *gojenkins.Job
 }
 
 // Name returns the job name.
@@ -28,16 +40,36 @@ func (j *Job) Description() string {
 	return j.jenkinsJob.GetDescription()
 }
 
-// Run runs the Job.
-func (j *Job) Run(args map[string]string, out chan Update) {
-	number, err := j.client.BuildJob(context.TODO(), j.Name(), args)
+// Parameters returns the parameters of the Job.
+func (j *Job) Parameters() []Parameter {
+	parameters := []Parameter{}
+	for _, property := range j.jenkinsJob.Raw.Property {
+		for _, definition := range property.ParameterDefinitions {
+			parameter := Parameter{
+				Name:        definition.Name,
+				Description: trim(definition.Description),
+			}
+			if value := definition.DefaultParameterValue.Value; value != nil {
+				parameter.Default = fmt.Sprint(value)
+				parameter.HasDefault = true
+			}
+			parameters = append(parameters, parameter)
+		}
+	}
+	return parameters
+}
+
+// Run runs the Job. It stops following the build when `ctx` is done,
+// and aborts it on the server when `ctx` is cancelled.
+func (j *Job) Run(ctx context.Context, args map[string]string, out chan Update) {
+	number, err := j.client.BuildJob(ctx, j.Name(), args)
 	if err != nil {
-		update(out, fmt.Sprintf("Job Invoke error %v", err), "boom", true)
+		fail(out, fmt.Errorf("job invoke error %w", err))
 		return
 	}
-	task, err := j.client.GetQueueItem(context.TODO(), number)
+	task, err := j.client.GetQueueItem(ctx, number)
 	if err != nil {
-		update(out, fmt.Sprintf("Task get error %v", err), "boom", true)
+		fail(out, fmt.Errorf("task get error %w", err))
 		return
 	}
 	update(out, fmt.Sprintf("Execution for job `%v` was queued", j.Name()), "stopwatch", false)
@@ -46,41 +78,85 @@ func (j *Job) Run(args map[string]string, out chan Update) {
 		if buildID != 0 {
 			break
 		}
-		time.Sleep(100 * time.Millisecond)
-		task.Poll(context.TODO())
+		if err := sleep(ctx, 100*time.Millisecond); err != nil {
+			if err == context.Canceled {
+				j.cancel(task, out)
+				return
+			}
+			fail(out, fmt.Errorf("stopped waiting for job `%v` to start: %w", j.Name(), err))
+			return
+		}
+		task.Poll(ctx)
 		buildID = task.Raw.Executable.Number
 	}
-	build, err := j.client.GetBuild(context.TODO(), j.Name(), buildID)
+	build, err := j.client.GetBuild(ctx, j.Name(), buildID)
 	if err != nil {
-		update(out, fmt.Sprintf("Queue item get error %v", err), "boom", true)
+		fail(out, fmt.Errorf("queue item get error %w", err))
 		return
 	}
-	update(out, fmt.Sprintf("Building `%v` with parameters `%v` (%v)", j.Name(), args, build.GetUrl()), "gear", false)
+	out <- Update{
+		Msg:      fmt.Sprintf("Building `%v` with parameters `%v` (%v)", j.Name(), args, build.GetUrl()),
+		Reaction: "gear",
+		Done:     false,
+		URL:      build.GetUrl(),
+	}
 	for {
 		if !build.Raw.Building {
 			break
 		}
-		time.Sleep(100 * time.Millisecond)
-		_, err = build.Poll(context.TODO())
+		if err := sleep(ctx, 100*time.Millisecond); err != nil {
+			if err == context.Canceled {
+				j.abort(build, out)
+				return
+			}
+			fail(out, fmt.Errorf("stopped following job `%v` (%v): %w", j.Name(), build.GetUrl(), err))
+			return
+		}
+		_, err = build.Poll(ctx)
 		if err != nil {
-			update(out, fmt.Sprintf("Error polling build %v", err), "boom", true)
+			fail(out, fmt.Errorf("error polling build %w", err))
 			return
 		}
 	}
+	if build.Raw.Result != gojenkins.STATUS_SUCCESS {
+		fail(out, fmt.Errorf("job `%v` completed with `%v` (%v)", j.Name(), build.Raw.Result, build.GetUrl()))
+		return
+	}
 	update(out, fmt.Sprintf("Job `%v` completed with `%v`", j.Name(), build.Raw.Result), "heavy_check_mark", true)
 }
 
+// cancel removes the queued `task` from the server.
+func (j *Job) cancel(task *gojenkins.Task, out chan Update) {
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	if _, err := task.Cancel(ctx); err != nil {
+		fail(out, fmt.Errorf("error cancelling queued job `%v`: %w", j.Name(), err))
+		return
+	}
+	fail(out, fmt.Errorf("queued job `%v` was cancelled: %w", j.Name(), context.Canceled))
+}
+
+// abort stops the running `build` on the server.
+func (j *Job) abort(build *gojenkins.Build, out chan Update) {
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	if _, err := build.Stop(ctx); err != nil {
+		fail(out, fmt.Errorf("error aborting job `%v` (%v): %w", j.Name(), build.GetUrl(), err))
+		return
+	}
+	fail(out, fmt.Errorf("job `%v` was aborted (%v): %w", j.Name(), build.GetUrl(), context.Canceled))
+}
+
 // Describe describes the Job.
 func (j *Job) Describe() string {
-	var err error
-	if j.describeTemplate == nil {
-		j.describeTemplate, err = DescribeTemplate()
-		if err != nil {
-			return fmt.Sprintf("Template parsing error: %s", err)
-		}
+	describeOnce.Do(func() {
+		describeTemplate, describeErr = DescribeTemplate()
+	})
+	if describeErr != nil {
+		return fmt.Sprintf("Template parsing error: %s", describeErr)
 	}
 	msg := &bytes.Buffer{}
-	err = j.describeTemplate.Execute(msg, j.jenkinsJob)
+	err := describeTemplate.Execute(msg, j.jenkinsJob)
 	if err != nil {
 		return fmt.Sprintf("Template execution error: %s", err)
 	}
@@ -121,6 +197,17 @@ func trim(in string) string {
 	)
 }
 
+// sleep waits for `d`, unless `ctx` is done before, in which case it
+// returns the context error.
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
 func update(out chan Update, reply, reaction string, done bool) {
 	out <- Update{
 		Msg:      reply,
@@ -128,3 +215,13 @@ func update(out chan Update, reply, reaction string, done bool) {
 		Done:     done,
 	}
 }
+
+// fail sends the last Update of a job which didn't succeed.
+func fail(out chan Update, err error) {
+	out <- Update{
+		Msg:      err.Error(),
+		Reaction: "boom",
+		Done:     true,
+		Err:      err,
+	}
+}
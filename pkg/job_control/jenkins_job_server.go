@@ -13,33 +13,35 @@ type JenkinsJobServer struct {
 }
 
 // Connect establishes connection to the JenkinsJobServer.
-func (js *JenkinsJobServer) Connect(url, user, password string) error {
+func (js *JenkinsJobServer) Connect(ctx context.Context, url, user, password string) error {
 	js.jenkins = gojenkins.CreateJenkins(nil, url, user, password)
-	_, err := js.jenkins.Init(context.TODO())
+	_, err := js.jenkins.Init(ctx)
 	if err != nil {
 		return err
 	}
 	js.jobs = &JobList{}
-	js.jobs.Clear()
-	err = js.Load()
+	err = js.Load(ctx)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// Load queries the job server for all the data.
-func (js *JenkinsJobServer) Load() error {
-	jobs, err := js.jenkins.GetAllJobs(context.TODO())
+// Load queries the job server for all the data, replacing the jobs
+// loaded before once all of them are read.
+func (js *JenkinsJobServer) Load(ctx context.Context) error {
+	jobs, err := js.jenkins.GetAllJobs(ctx)
 	if err != nil {
 		return err
 	}
+	loaded := []IJob{}
 	for _, job := range jobs {
-		js.jobs.AddJob(&Job{
+		loaded = append(loaded, &Job{
 			jenkinsJob: job,
 			client:     js.jenkins,
 		})
 	}
+	js.jobs.Replace(loaded)
 	return nil
 }
 
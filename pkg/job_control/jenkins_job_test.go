@@ -99,6 +99,23 @@ func TestJob(t *testing.T) {
 				},
 			},
 		},
+		"Simple job with an empty default": {
+			name:        "myjob",
+			description: "myjob does something",
+			params: []struct {
+				Name         string
+				Type         string
+				Description  string
+				DefaultValue string
+			}{
+				{
+					Name:         "myParam",
+					Type:         "StringParameterDefinition",
+					Description:  "myParam helps parametrize myjob",
+					DefaultValue: "",
+				},
+			},
+		},
 	}
 
 	for testID, tc := range tcs {
@@ -121,6 +138,16 @@ func TestJob(t *testing.T) {
 			if describe != tc.Describe() {
 				t.Errorf("Wrong job describe '%v' should be '%v'", describe, tc.Describe())
 			}
+
+			parameters := j.Parameters()
+			if len(parameters) != len(tc.params) {
+				t.Fatalf("Wrong job parameters %v should be %v", parameters, tc.params)
+			}
+			for i, parameter := range parameters {
+				if parameter.Name != tc.params[i].Name || parameter.Default != tc.params[i].DefaultValue || !parameter.HasDefault {
+					t.Errorf("Wrong job parameter %v should be %v", parameter, tc.params[i])
+				}
+			}
 		})
 	}
 }
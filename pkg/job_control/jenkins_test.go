@@ -1,10 +1,12 @@
 package jobcontrol
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -46,9 +48,23 @@ func TestParsing(t *testing.T) {
 			input:         "build  deploy INDEX=\"users ducks\"",
 			command:       "build",
 			expectedJob:   "deploy",
-			expectedArgs:  map[string]string{"INDEX": "\"users ducks\""},
+			expectedArgs:  map[string]string{"INDEX": "users ducks"},
 			expectedError: "",
 		},
+		{
+			input:         "build deploy URL='https://example.com/?a=b' MSG=it\\'s",
+			command:       "build",
+			expectedJob:   "deploy",
+			expectedArgs:  map[string]string{"URL": "https://example.com/?a=b", "MSG": "it's"},
+			expectedError: "",
+		},
+		{
+			input:         "build deploy INDEX=\"users ducks",
+			command:       "build",
+			expectedJob:   "",
+			expectedArgs:  map[string]string{},
+			expectedError: "there's an unbalanced `\"` quote. Close it, or escape it like `\\\"`",
+		},
 		{
 			input:         "describe",
 			command:       "describe",
@@ -133,7 +149,7 @@ func TestLoadReload(t *testing.T) {
 
 	msg := synthetic.NewMockMessage("", false)
 
-	j.Reload(msg)
+	j.Reload(context.Background(), msg)
 
 	if j.js.GetJobs().Len() != len(tc.expectedJobs) {
 		t.Errorf("Wrong number of jobs loaded %v but expected %v", j.js.GetJobs().Len(), len(tc.expectedJobs))
@@ -153,6 +169,27 @@ func TestLoadReload(t *testing.T) {
 	}
 }
 
+func TestReloadConcurrently(t *testing.T) {
+	disableLogs()
+	j := &Jenkins{
+		js: NewMockJobServer(map[string]string{"build": "Build the project"}),
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			j.Reload(context.Background(), synthetic.NewMockMessage("", false))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if j.js.GetJob("build") == nil || len(j.js.GetJobs().Jobs()) != 1 {
+			t.Fatalf("The job `build` is missing while reloading")
+		}
+	}
+	<-done
+}
+
 func TestDescribe(t *testing.T) {
 	disableLogs()
 	tc := loadTC{
@@ -215,8 +252,8 @@ func TestBuild(t *testing.T) {
 			"test":   "Run test suit on the project",
 			"deploy": "Deploy project",
 		},
+		// The queued reply is edited once the job is building.
 		expectedRepliesOnBuild: []string{
-			"Execution for job `test` was queued",
 			fmt.Sprintf("Building `test` with parameters `map[]` (%v/job/test)", os.Getenv("JENKINS_URL")),
 			"Job test completed",
 		},
@@ -227,8 +264,9 @@ func TestBuild(t *testing.T) {
 		),
 	}
 	msg := synthetic.NewMockMessage("build test", true)
+	tracker := &MockTracker{}
 
-	j.Build(msg)
+	j.Build(context.Background(), msg, tracker, nil)
 
 	if len(msg.Replies()) != len(tc.expectedRepliesOnBuild) {
 		t.Errorf("Wrong number of replies %v but expected %v", len(msg.Replies()), len(tc.expectedRepliesOnBuild))
@@ -238,46 +276,64 @@ func TestBuild(t *testing.T) {
 			t.Errorf("Wrong reply '%v' but expected '%v'", reply, tc.expectedRepliesOnBuild[i])
 		}
 	}
+	expectedURL := fmt.Sprintf("%v/job/test", os.Getenv("JENKINS_URL"))
+	if len(tracker.URLs) != 1 || tracker.URLs[0] != expectedURL {
+		t.Errorf("Wrong URLs tracked %v but expected %v", tracker.URLs, []string{expectedURL})
+	}
 }
 
-func TestTokenizeParams(t *testing.T) {
-	tt := []struct {
-		input  string
-		result []string
-	}{
-		{
-			input:  "",
-			result: []string{},
-		},
-		{
-			input:  "build deploy",
-			result: []string{"build", "deploy"},
-		},
-		{
-			input:  "build  deploy      INDEX=users",
-			result: []string{"build", "deploy", "INDEX=users"},
-		},
-		{
-			input:  "build  deploy      INDEX=\"users\"",
-			result: []string{"build", "deploy", "INDEX=\"users\""},
-		},
-		{
-			input:  "build  deploy      INDEX=\"users ducks\"",
-			result: []string{"build", "deploy", "INDEX=\"users ducks\""},
-		},
+func TestBuildFailing(t *testing.T) {
+	disableLogs()
+	j := &Jenkins{
+		js: NewMockJobServer(map[string]string{}),
 	}
-	for _, tc := range tt {
-		t.Run(tc.input, func(t *testing.T) {
-			result := tokenizeParams(tc.input)
-			if len(result) != len(tc.result) {
-				t.Errorf("expected %d results but got %d", len(tc.result), len(result))
-			}
+	j.js.GetJobs().AddJob(&MockJob{name: "migrate", result: "FAILURE"})
+	msg := synthetic.NewMockMessage("build migrate", true)
 
-			for i, value := range result {
-				if value != tc.result[i] {
-					t.Errorf("expected element %d to be %s but was %s", i, tc.result[i], value)
-				}
-			}
-		})
+	err := j.Build(context.Background(), msg, &MockTracker{}, nil)
+
+	if err == nil || err.Error() != "job `migrate` completed with `FAILURE`" {
+		t.Errorf("Wrong error %v but expected the job failure", err)
+	}
+	if len(msg.Replies()) != 1 {
+		t.Errorf("Wrong replies %v but expected 1, without the failure", msg.Replies())
+	}
+}
+
+func TestBuildAskingParameters(t *testing.T) {
+	disableLogs()
+	j := &Jenkins{
+		js: NewMockJobServer(map[string]string{}),
+	}
+	j.js.GetJobs().AddJob(&MockJob{
+		name:        "deploy",
+		description: "Deploy project",
+		parameters: []Parameter{
+			{Name: "ENV", Description: "Environment to deploy to"},
+			{Name: "INDEX", Description: "Index to rebuild"},
+			{Name: "BRANCH", Default: "master", HasDefault: true},
+			{Name: "TAG", Description: "Tag to deploy, or none", HasDefault: true},
+		},
+	})
+	msg := synthetic.NewMockMessage("build deploy INDEX=users", true)
+	asker := &MockAsker{Answers: []string{"staging"}}
+	tracker := &MockTracker{}
+
+	err := j.Build(context.Background(), msg, tracker, asker)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expectedQuestions := []string{"What's the value of *ENV* for `deploy`? Environment to deploy to"}
+	if len(asker.Questions) != len(expectedQuestions) || asker.Questions[0] != expectedQuestions[0] {
+		t.Errorf("Wrong questions %v but expected %v", asker.Questions, expectedQuestions)
+	}
+	expectedReply := fmt.Sprintf("Building `deploy` with parameters `map[ENV:staging INDEX:users]` (%v/job/deploy)", os.Getenv("JENKINS_URL"))
+	if len(msg.Replies()) != 2 || msg.Replies()[0] != expectedReply {
+		t.Errorf("Wrong replies %v but expected '%v'", msg.Replies(), expectedReply)
+	}
+	expectedParams := map[string]string{"ENV": "staging", "INDEX": "users"}
+	if !reflect.DeepEqual(tracker.Params, expectedParams) {
+		t.Errorf("Wrong parameters tracked %v but expected %v", tracker.Params, expectedParams)
 	}
 }
@@ -1,9 +1,23 @@
 package jobcontrol
 
+import "context"
+
 // IJob is an interface to a job.
 type IJob interface {
 	Name() string
 	Description() string
-	Run(map[string]string, chan Update)
+	Parameters() []Parameter
+	Run(context.Context, map[string]string, chan Update)
 	Describe() string
 }
+
+// Parameter describes a parameter of a job.
+type Parameter struct {
+	Name        string
+	Description string
+	// Default is the value used when the parameter isn't given.
+	Default string
+	// HasDefault tells whether the job declares a Default, even an
+	// empty one. Parameters without it must be given.
+	HasDefault bool
+}
@@ -1,40 +1,55 @@
 package jobcontrol
 
-import (
-	"fmt"
-)
+import "sync"
 
 // IJobList is an interface to a collection of jobs.
 type IJobList interface {
 	AddJob(IJob)
 	Len() int
 	Clear()
+	Replace([]IJob)
 	GetJob(string) IJob
-	List() string
+	Jobs() []IJob
 }
 
-// JobList is an implementation of an IJobList.
+// JobList is an implementation of an IJobList. It's safe for
+// concurrent use.
 type JobList struct {
+	sync.RWMutex
 	jobs []IJob
 }
 
 // AddJob adds a new IJob to JobList.
 func (jl *JobList) AddJob(job IJob) {
+	jl.Lock()
+	defer jl.Unlock()
 	jl.jobs = append(jl.jobs, job)
 }
 
 // Len returns the length of the job list.
 func (jl *JobList) Len() int {
+	jl.RLock()
+	defer jl.RUnlock()
 	return len(jl.jobs)
 }
 
 // Clear resets the object.
 func (jl *JobList) Clear() {
-	jl.jobs = []IJob{}
+	jl.Replace([]IJob{})
+}
+
+// Replace swaps all the jobs in the list by `jobs` at once, so they're
+// never missing while being loaded again.
+func (jl *JobList) Replace(jobs []IJob) {
+	jl.Lock()
+	defer jl.Unlock()
+	jl.jobs = jobs
 }
 
 // GetJob retrieves a job identified by name.
 func (jl *JobList) GetJob(name string) IJob {
+	jl.RLock()
+	defer jl.RUnlock()
 	for _, job := range jl.jobs {
 		if job.Name() == name {
 			return job
@@ -43,11 +58,9 @@ func (jl *JobList) GetJob(name string) IJob {
 	return nil
 }
 
-// List returns a string with a list of job names.
-func (jl *JobList) List() string {
-	result := ""
-	for _, job := range jl.jobs {
-		result = fmt.Sprintf("%s%s\n", result, job.Name())
-	}
-	return result
+// Jobs returns a copy of the jobs in the list.
+func (jl *JobList) Jobs() []IJob {
+	jl.RLock()
+	defer jl.RUnlock()
+	return append([]IJob{}, jl.jobs...)
 }
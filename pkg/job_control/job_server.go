@@ -1,9 +1,11 @@
 package jobcontrol
 
+import "context"
+
 // IJobServer is an interface to a job server.
 type IJobServer interface {
-	Connect(string, string, string) error
-	Load() error
+	Connect(context.Context, string, string, string) error
+	Load(context.Context) error
 	GetJobs() IJobList
 	GetJob(string) IJob
 }
@@ -1,6 +1,7 @@
 package jobcontrol
 
 import (
+	"context"
 	"fmt"
 	"os"
 )
@@ -17,24 +18,25 @@ func NewMockJobServer(jobs map[string]string) *MockJobServer {
 		jobs:         &JobList{},
 		originalJobs: jobs,
 	}
-	jobServer.Load()
+	jobServer.Load(context.Background())
 	return jobServer
 }
 
 // Connect mocks JobServer.Connect method.
-func (mjs *MockJobServer) Connect(string, string, string) error {
+func (mjs *MockJobServer) Connect(context.Context, string, string, string) error {
 	return nil
 }
 
 // Load mocks JobServer.Load method.
-func (mjs *MockJobServer) Load() error {
+func (mjs *MockJobServer) Load(ctx context.Context) error {
+	loaded := []IJob{}
 	for jobName := range mjs.originalJobs {
-		mjs.jobs.AddJob(&MockJob{
+		loaded = append(loaded, &MockJob{
 			name:        jobName,
 			description: mjs.originalJobs[jobName],
 		})
-
 	}
+	mjs.jobs.Replace(loaded)
 	return nil
 }
 
@@ -52,6 +54,9 @@ func (mjs *MockJobServer) GetJob(jobName string) IJob {
 type MockJob struct {
 	name        string
 	description string
+	parameters  []Parameter
+	// result is the result of the builds, SUCCESS when empty.
+	result string
 }
 
 // Name mocks Job.Name method.
@@ -64,8 +69,13 @@ func (j *MockJob) Description() string {
 	return j.description
 }
 
+// Parameters mocks Job.Parameters method.
+func (j *MockJob) Parameters() []Parameter {
+	return j.parameters
+}
+
 // Run mocks Job.Run method.
-func (j *MockJob) Run(args map[string]string, out chan Update) {
+func (j *MockJob) Run(ctx context.Context, args map[string]string, out chan Update) {
 	out <- Update{
 		Msg: fmt.Sprintf(
 			"Execution for job `%s` was queued",
@@ -84,6 +94,17 @@ func (j *MockJob) Run(args map[string]string, out chan Update) {
 		),
 		Reaction: "gear",
 		Done:     false,
+		URL:      fmt.Sprintf("%s/job/%s", os.Getenv("JENKINS_URL"), j.name),
+	}
+	if j.result != "" && j.result != "SUCCESS" {
+		err := fmt.Errorf("job `%s` completed with `%s`", j.name, j.result)
+		out <- Update{
+			Msg:      err.Error(),
+			Reaction: "boom",
+			Done:     true,
+			Err:      err,
+		}
+		return
 	}
 	out <- Update{
 		Msg: fmt.Sprintf(
@@ -99,3 +120,37 @@ func (j *MockJob) Run(args map[string]string, out chan Update) {
 func (j *MockJob) Describe() string {
 	return j.Description()
 }
+
+// MockTracker mocks a Tracker.
+type MockTracker struct {
+	URLs   []string
+	Params map[string]string
+}
+
+// Track mocks Tracker.Track method.
+func (t *MockTracker) Track(url string) {
+	t.URLs = append(t.URLs, url)
+}
+
+// TrackParams mocks Tracker.TrackParams method.
+func (t *MockTracker) TrackParams(params map[string]string) {
+	t.Params = params
+}
+
+// MockAsker mocks an Asker, answering the questions with Answers in
+// order.
+type MockAsker struct {
+	Questions []string
+	Answers   []string
+}
+
+// Ask mocks Asker.Ask method.
+func (a *MockAsker) Ask(question string) (string, error) {
+	a.Questions = append(a.Questions, question)
+	if len(a.Answers) == 0 {
+		return "", fmt.Errorf("no answer to `%s`", question)
+	}
+	answer := a.Answers[0]
+	a.Answers = a.Answers[1:]
+	return answer, nil
+}
@@ -1,8 +1,12 @@
 package jobcontrol
 
-// Update is a message update.
+// Update is a message update. URL is the build page, once the job
+// started building. Err is set in the last Update when the job didn't
+// succeed.
 type Update struct {
 	Msg      string
 	Reaction string
 	Done     bool
+	URL      string
+	Err      error
 }
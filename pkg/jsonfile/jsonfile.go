@@ -0,0 +1,22 @@
+// Package jsonfile keeps values in JSON files.
+package jsonfile
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Save writes `value` as indented JSON to `filename`, readable only by
+// its owner. It writes a temporary file first and renames it, so the
+// file is replaced atomically and never left half written.
+func Save(filename string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
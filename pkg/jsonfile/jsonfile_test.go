@@ -0,0 +1,33 @@
+package jsonfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSave(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "values.json")
+	if err := os.WriteFile(filename, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Save(filename, map[string][]int{"ids": {1, 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  \"ids\": [\n    1,\n    2\n  ]\n}"
+	if string(data) != expected {
+		t.Errorf("wrong content %q should be %q", data, expected)
+	}
+	if _, err := os.Stat(filename + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("the temporary file is left: %v", err)
+	}
+	if err := Save(filename, func() {}); err == nil {
+		t.Errorf("values that can't be encoded should fail")
+	}
+}
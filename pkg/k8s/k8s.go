@@ -3,6 +3,7 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	v1 "k8s.io/api/core/v1"
@@ -45,13 +46,13 @@ var getClient = func(cluster string) (kubernetes.Interface, error) {
 // GetPods returns a list of pods for the specified `cluster`. If
 // `cluster` is an empty string, then it lists all pods in all
 // clusters known by the bot.
-func GetPods(cluster, namespace string) ([]v1.Pod, error) {
+func GetPods(ctx context.Context, cluster, namespace string) ([]v1.Pod, error) {
 	client, err := getClient(cluster)
 	if err != nil {
 		return nil, err
 	}
 
-	pods, err := client.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{})
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -59,29 +60,30 @@ func GetPods(cluster, namespace string) ([]v1.Pod, error) {
 	return pods.Items, nil
 }
 
-// ListPods returns a list of pods
-func ListPods(msg synthetic.Message) {
-	command := strings.Split(msg.Text(), " ")
-	cluster := ""
-	namespace := ""
-	if len(command) >= 3 {
-		cluster = command[2]
-		if len(command) == 4 {
-			namespace = command[3]
-		}
-	}
-
-	pods, err := GetPods(cluster, namespace)
+// ListPods replies `msg` with the list of pods in `namespace` of
+// `cluster`.
+func ListPods(ctx context.Context, msg synthetic.Message, cluster, namespace string) error {
+	pods, err := GetPods(ctx, cluster, namespace)
 	if err != nil {
-		msg.Reply(err.Error(), msg.Thread())
-		return
+		return err
 	}
 
-	response := ""
+	if len(pods) == 0 {
+		msg.Reply("There are no pods there.", msg.Thread())
+		return nil
+	}
+	table := &synthetic.Table{Columns: []string{"Pod", "Namespace", "Status"}}
 	for _, pod := range pods {
-		response = fmt.Sprintf("%s- %s\n", response, pod.Name)
+		table.Rows = append(table.Rows, []string{pod.Name, pod.Namespace, string(pod.Status.Phase)})
 	}
-	msg.Reply(response, msg.Thread())
+	msg.ReplyResponse(&synthetic.Response{
+		Header: "Pods",
+		Blocks: []synthetic.Block{
+			table,
+			synthetic.Context(fmt.Sprintf("%d pods", len(pods))),
+		},
+	}, msg.Thread())
+	return nil
 }
 
 // GetClusters loads default kubeconfig and gets the list of cluster
@@ -103,19 +105,23 @@ func GetClusters() ([]string, error) {
 
 // ListClusters returns a list of clusters available in the supplied
 // kubeconfig
-func ListClusters(msg synthetic.Message) {
+func ListClusters(msg synthetic.Message) error {
 	clusters, err := GetClusters()
 	if err != nil {
-		msg.Reply(err.Error(), msg.Thread())
-		return
+		return err
 	}
 	if len(clusters) < 1 {
 		msg.Reply("I know of no kubernetes clusters. Checkout my kubeconfig.", msg.Thread())
-		return
+		return nil
 	}
-	response := "I know of the following clusters:\n"
+	sort.Strings(clusters)
+	response := ""
 	for _, cluster := range clusters {
 		response = fmt.Sprintf("%s- %s\n", response, cluster)
 	}
-	msg.Reply(response, msg.Thread())
+	msg.ReplyResponse(&synthetic.Response{
+		Header: "Kubernetes clusters",
+		Blocks: []synthetic.Block{synthetic.Section(strings.TrimSuffix(response, "\n"))},
+	}, msg.Thread())
+	return nil
 }
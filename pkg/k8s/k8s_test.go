@@ -11,6 +11,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
 )
 
 func stringIn(item string, items []string) bool {
@@ -90,7 +92,7 @@ func TestGetPods(t *testing.T) {
 			return clientSet, nil
 		}
 
-		pods, err := GetPods("", test.namespace)
+		pods, err := GetPods(context.Background(), "", test.namespace)
 		if err != nil {
 			panic(err)
 		}
@@ -106,6 +108,60 @@ func TestGetPods(t *testing.T) {
 	}
 }
 
+func TestListPods(t *testing.T) {
+	tcs := []struct {
+		namespace string
+		podList   []*v1.Pod
+		expected  string
+	}{
+		{
+			namespace: "",
+			podList: []*v1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "pod1",
+						Namespace: "default",
+					},
+					Status: v1.PodStatus{Phase: v1.PodRunning},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "pod2",
+						Namespace: "kube-system",
+					},
+					Status: v1.PodStatus{Phase: v1.PodPending},
+				},
+			},
+			expected: "*Pods*\n```\nPod   Namespace    Status\npod1  default      Running\npod2  kube-system  Pending\n```\n_2 pods_",
+		},
+		{
+			namespace: "monitoring",
+			podList:   []*v1.Pod{},
+			expected:  "There are no pods there.",
+		},
+	}
+
+	for _, test := range tcs {
+		clientSet := fake.NewSimpleClientset()
+		getClient = func(cluster string) (kubernetes.Interface, error) {
+			for _, pod := range test.podList {
+				clientSet.CoreV1().Pods(pod.Namespace).Create(context.Background(), pod, metav1.CreateOptions{})
+			}
+			return clientSet, nil
+		}
+		msg := synthetic.NewMockMessage("pods", true)
+
+		err := ListPods(context.Background(), msg, "", test.namespace)
+		if err != nil {
+			panic(err)
+		}
+
+		if len(msg.Replies()) != 1 || msg.Replies()[0] != test.expected {
+			t.Errorf("Wrong replies %q, expected %q", msg.Replies(), test.expected)
+		}
+	}
+}
+
 func TestGetConfig(t *testing.T) {
 	tcs := []struct {
 		kubeconfig    string
@@ -182,3 +238,33 @@ func TestListClusters(t *testing.T) {
 		}
 	}
 }
+
+func TestListClustersReply(t *testing.T) {
+	tcs := []struct {
+		kubeconfig string
+		expected   string
+	}{
+		{
+			kubeconfig: getKubeCfgFixture("kubeconfig.yaml"),
+			expected:   "*Kubernetes clusters*\n- cluster1.example.com\n- cluster2.example.com\n- cluster3.example.com",
+		},
+		{
+			kubeconfig: getKubeCfgFixture("kubeconfig_one_missing.yaml"),
+			expected:   "*Kubernetes clusters*\n- cluster1.example.com\n- cluster2.example.com",
+		},
+	}
+
+	for _, test := range tcs {
+		os.Setenv("KUBECONFIG", test.kubeconfig)
+		msg := synthetic.NewMockMessage("clusters", true)
+
+		err := ListClusters(msg)
+		if err != nil {
+			panic(err)
+		}
+
+		if len(msg.Replies()) != 1 || msg.Replies()[0] != test.expected {
+			t.Errorf("Wrong replies %q, expected %q", msg.Replies(), test.expected)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ifosch/synthetic/pkg/command"
+	"github.com/ifosch/synthetic/pkg/environ"
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+const (
+	// registerTimeout limits how long a plugin can take to register
+	// its commands once started.
+	registerTimeout = 10 * time.Second
+	// closeTimeout limits how long a plugin can take to exit once
+	// its standard input is closed, before it's killed.
+	closeTimeout = 5 * time.Second
+	// maxLineSize limits the size of the lines written by a plugin,
+	// which is killed when it writes a longer one.
+	maxLineSize = 16 * 1024 * 1024
+)
+
+// request is a command run by a plugin.
+type request struct {
+	msg  synthetic.Message
+	done chan error
+}
+
+// Plugin is an executable providing commands, run as a subprocess
+// speaking the JSON Lines protocol described in Message.
+type Plugin struct {
+	name     string
+	cmd      *exec.Cmd
+	commands []Spec
+
+	writeMutex sync.Mutex
+	stdin      io.WriteCloser
+
+	requestsMutex sync.Mutex
+	requests      map[string]*request
+	lastID        int
+
+	exited chan struct{}
+}
+
+// Load starts every executable file in `dir` as a Plugin, with the
+// environment variables in `env`. The files failing to start are
+// logged and skipped.
+func Load(dir string, env []string) ([]*Plugin, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	plugins := []*Plugin{}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		p, err := Start(name, filepath.Join(dir, entry.Name()), env)
+		if err != nil {
+			log.Printf("Error starting the plugin %v: %v", entry.Name(), err)
+			continue
+		}
+		plugins = append(plugins, p)
+	}
+	return plugins, nil
+}
+
+// Start runs the executable in `path` with `args` as the Plugin
+// `name`, and waits for it to register its commands. The plugin only
+// gets the environment variables in `env`, and PATH and HOME, so the
+// credentials of the bot aren't given to them.
+func Start(name, path string, env []string, args ...string) (*Plugin, error) {
+	p := &Plugin{
+		name:     name,
+		cmd:      exec.Command(path, args...),
+		requests: map[string]*request{},
+		exited:   make(chan struct{}),
+	}
+	p.cmd.Env = environ.Allowed(env)
+	p.cmd.Stderr = &logWriter{prefix: fmt.Sprintf("Plugin %v: ", name)}
+	stdin, err := p.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	p.stdin = stdin
+	stdout, err := p.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	lines := make(chan []byte)
+	go p.read(stdout, lines)
+
+	if err := p.register(lines); err != nil {
+		p.stdin.Close()
+		p.cmd.Process.Kill()
+		go func() {
+			for range lines {
+			}
+		}()
+		p.cmd.Wait()
+		return nil, err
+	}
+	go p.listen(lines)
+	return p, nil
+}
+
+// read sends the lines written by the Plugin to `stdout` to `lines`,
+// closing it once the Plugin exits. The Plugin is killed when it
+// can't be read, like when it writes a line longer than maxLineSize.
+func (p *Plugin) read(stdout io.Reader, lines chan []byte) {
+	defer close(lines)
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		lines <- append([]byte{}, scanner.Bytes()...)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Error reading the plugin %v, killing it: %v", p.name, err)
+		p.cmd.Process.Kill()
+	}
+}
+
+// register reads the commands of the Plugin from the first line it
+// writes.
+func (p *Plugin) register(lines chan []byte) error {
+	var line []byte
+	select {
+	case line = <-lines:
+	case <-time.After(registerTimeout):
+		return fmt.Errorf("the plugin didn't register its commands within %v", registerTimeout)
+	}
+	if line == nil {
+		return fmt.Errorf("the plugin exited before registering its commands")
+	}
+	msg := &Message{}
+	if err := json.Unmarshal(line, msg); err != nil {
+		return fmt.Errorf("error reading the registration of the plugin: %w", err)
+	}
+	if msg.Type != TypeRegister {
+		return fmt.Errorf("the plugin must register its commands first, but it sent `%s`", msg.Type)
+	}
+	for _, spec := range msg.Commands {
+		if spec.Name == "" || spec.Verb == "" {
+			return fmt.Errorf("the plugin registered a command without name or verb: %+v", spec)
+		}
+	}
+	p.commands = msg.Commands
+	return nil
+}
+
+// listen handles the Messages written by the Plugin until it exits,
+// failing then the commands it was running.
+func (p *Plugin) listen(lines chan []byte) {
+	for line := range lines {
+		msg := &Message{}
+		if err := json.Unmarshal(line, msg); err != nil {
+			log.Printf("Plugin %v wrote a wrong message %q: %v", p.name, line, err)
+			continue
+		}
+		p.handle(msg)
+	}
+	if err := p.cmd.Wait(); err != nil {
+		log.Printf("Plugin %v exited: %v", p.name, err)
+	}
+	close(p.exited)
+}
+
+// handle runs the action of a Message written by the Plugin.
+func (p *Plugin) handle(msg *Message) {
+	p.requestsMutex.Lock()
+	r, ok := p.requests[msg.ID]
+	if ok && msg.Type == TypeDone {
+		delete(p.requests, msg.ID)
+	}
+	p.requestsMutex.Unlock()
+	if !ok {
+		// Cancelled commands may still be done afterwards.
+		if msg.Type != TypeDone {
+			log.Printf("Plugin %v wrote a message for the unknown command `%v`: %v", p.name, msg.ID, msg.Type)
+		}
+		return
+	}
+
+	switch msg.Type {
+	case TypeReply:
+		if msg.Ephemeral {
+			r.msg.ReplyEphemeral(msg.Text, msg.Thread || r.msg.Thread())
+			break
+		}
+		r.msg.Reply(msg.Text, msg.Thread || r.msg.Thread())
+	case TypeReact:
+		r.msg.React(msg.Reaction)
+	case TypeUnreact:
+		r.msg.Unreact(msg.Reaction)
+	case TypeUpload:
+		if _, err := r.msg.Upload(msg.Name, msg.ContentType, bytes.NewReader(msg.Content), msg.Text); err != nil {
+			log.Printf("Error uploading %v for plugin %v: %v", msg.Name, p.name, err)
+		}
+	case TypeDone:
+		if msg.Error != "" {
+			r.done <- errors.New(msg.Error)
+		}
+		close(r.done)
+	default:
+		log.Printf("Plugin %v wrote a message of unknown type `%v`", p.name, msg.Type)
+	}
+}
+
+// send writes `msg` to the Plugin.
+func (p *Plugin) send(msg *Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	p.writeMutex.Lock()
+	defer p.writeMutex.Unlock()
+	_, err = p.stdin.Write(append(line, '\n'))
+	return err
+}
+
+// Name returns the name of the Plugin.
+func (p *Plugin) Name() string {
+	return p.name
+}
+
+// Commands returns the commands registered by the Plugin.
+func (p *Plugin) Commands() []Spec {
+	return p.commands
+}
+
+// Register registers the commands of the Plugin in `handler`, named
+// like `plugin.<plugin>.<command>`.
+func (p *Plugin) Register(handler *command.Handler) error {
+	for i := range p.commands {
+		spec := &p.commands[i]
+		commandSpec, err := spec.commandSpec()
+		if err != nil {
+			return fmt.Errorf("error in the plugin %s: %w", p.name, err)
+		}
+		name := fmt.Sprintf("plugin.%s.%s", p.name, spec.Name)
+		if err := handler.RegisterCommand(name, commandSpec, p.executor(spec)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// executor returns the ExecutorFunc running the command `spec` in
+// the Plugin.
+func (p *Plugin) executor(spec *Spec) command.ExecutorFunc {
+	return func(c *command.Command) error {
+		p.requestsMutex.Lock()
+		p.lastID++
+		id := strconv.Itoa(p.lastID)
+		r := &request{msg: c.Message(), done: make(chan error, 1)}
+		p.requests[id] = r
+		p.requestsMutex.Unlock()
+		defer func() {
+			p.requestsMutex.Lock()
+			delete(p.requests, id)
+			p.requestsMutex.Unlock()
+		}()
+
+		if err := p.send(&Message{Type: TypeCommand, ID: id, Command: newPayload(spec, c)}); err != nil {
+			return fmt.Errorf("error sending `%s` to the plugin %s: %w", c.Name(), p.name, err)
+		}
+		select {
+		case err := <-r.done:
+			return err
+		case <-p.exited:
+			return fmt.Errorf("the plugin %s exited while running `%s`", p.name, c.Message().Text())
+		case <-c.Context().Done():
+			if err := p.send(&Message{Type: TypeCancel, ID: id}); err != nil {
+				log.Printf("Error cancelling %v in plugin %v: %v", id, p.name, err)
+			}
+			return fmt.Errorf("`%s` was stopped: %w", c.Message().Text(), c.Context().Err())
+		}
+	}
+}
+
+// Close closes the standard input of the Plugin, so it exits, and
+// kills it if it doesn't exit in time.
+func (p *Plugin) Close() error {
+	err := p.stdin.Close()
+	select {
+	case <-p.exited:
+	case <-time.After(closeTimeout):
+		log.Printf("Plugin %v didn't exit within %v, killing it", p.name, closeTimeout)
+		p.cmd.Process.Kill()
+		<-p.exited
+	}
+	return err
+}
+
+// logWriter logs the lines written to it.
+type logWriter struct {
+	prefix string
+}
+
+func (w *logWriter) Write(data []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		log.Printf("%s%s", w.prefix, line)
+	}
+	return len(data), nil
+}
@@ -0,0 +1,205 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ifosch/synthetic/pkg/command"
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+func disableLogs() {
+	log.SetFlags(0)
+	log.SetOutput(io.Discard)
+}
+
+// helperArgs are the arguments running TestHelperPlugin as a plugin
+// in the test binary.
+var helperArgs = []string{"-test.run=TestHelperPlugin", "--", "helper-plugin"}
+
+// TestHelperPlugin isn't a test, but the plugin run by the tests, in
+// the test binary itself.
+func TestHelperPlugin(t *testing.T) {
+	if os.Args[len(os.Args)-1] != "helper-plugin" {
+		return
+	}
+	out := json.NewEncoder(os.Stdout)
+	out.Encode(&Message{Type: TypeRegister, Commands: []Spec{
+		{Name: "echo", Verb: "echo", Args: []Arg{{Name: "text", Required: true, Variadic: true}}, Flags: []string{"loud"}, Summary: "Echoes the text"},
+		{Name: "fail", Verb: "fail", Summary: "Always fails"},
+		{Name: "wait", Verb: "wait", Summary: "Waits to be cancelled"},
+		{Name: "flood", Verb: "flood", Summary: "Replies a line too long"},
+		{Name: "getenv", Verb: "getenv", Summary: "Replies some environment variables"},
+	}})
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		msg := &Message{}
+		if err := json.Unmarshal(scanner.Bytes(), msg); err != nil {
+			fmt.Fprintf(os.Stderr, "wrong message: %v\n", err)
+			continue
+		}
+		switch {
+		case msg.Type == TypeCancel:
+			out.Encode(&Message{Type: TypeDone, ID: msg.ID, Error: "cancelled"})
+		case msg.Command.Name == "echo":
+			text := msg.Command.Args["text"]
+			if msg.Command.Flags["loud"] {
+				text += "!"
+			}
+			out.Encode(&Message{Type: TypeReact, ID: msg.ID, Reaction: "eyes"})
+			out.Encode(&Message{Type: TypeReply, ID: msg.ID, Text: fmt.Sprintf("%s says %s in %s", msg.Command.User.Name, text, msg.Command.Conversation.Name)})
+			out.Encode(&Message{Type: TypeUpload, ID: msg.ID, Name: "echo.txt", ContentType: "text/plain", Content: []byte(text), Text: "Echoed"})
+			out.Encode(&Message{Type: TypeDone, ID: msg.ID})
+		case msg.Command.Name == "fail":
+			out.Encode(&Message{Type: TypeDone, ID: msg.ID, Error: "it failed"})
+		case msg.Command.Name == "getenv":
+			out.Encode(&Message{Type: TypeReply, ID: msg.ID, Text: fmt.Sprintf("shared=%s secret=%s", os.Getenv("SYNTHETIC_SHARED"), os.Getenv("SYNTHETIC_SECRET"))})
+			out.Encode(&Message{Type: TypeDone, ID: msg.ID})
+		case msg.Command.Name == "flood":
+			out.Encode(&Message{Type: TypeReply, ID: msg.ID, Text: strings.Repeat("a", maxLineSize)})
+		}
+	}
+	os.Exit(0)
+}
+
+// writePlugin writes an executable running TestHelperPlugin in `dir`.
+func writePlugin(t *testing.T, dir, name string) {
+	script := fmt.Sprintf("#!/bin/sh\nexec '%s' %s\n", os.Args[0], strings.Join(helperArgs, " "))
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	disableLogs()
+	dir := t.TempDir()
+	writePlugin(t, dir, "helper.sh")
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("Not a plugin"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken"), []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	plugins, err := Load(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, p := range plugins {
+			p.Close()
+		}
+	}()
+
+	if len(plugins) != 1 || plugins[0].Name() != "helper" {
+		t.Fatalf("wrong plugins %v loaded", plugins)
+	}
+	names := []string{}
+	for _, spec := range plugins[0].Commands() {
+		names = append(names, spec.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"echo", "fail", "wait", "flood", "getenv"}) {
+		t.Errorf("wrong commands %v registered", names)
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	disableLogs()
+	os.Setenv("SYNTHETIC_SHARED", "yes")
+	os.Setenv("SYNTHETIC_SECRET", "xoxb-secret")
+	defer os.Unsetenv("SYNTHETIC_SHARED")
+	defer os.Unsetenv("SYNTHETIC_SECRET")
+	p, err := Start("helper", os.Args[0], []string{"SYNTHETIC_SHARED"}, helperArgs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	h := command.NewHandler()
+	h.SetTimeout(time.Second)
+	if err := p.Register(h); err != nil {
+		t.Fatal(err)
+	}
+
+	tt := map[string]struct {
+		text     string
+		replies  []string
+		uploads  []synthetic.MockUpload
+		failures int
+	}{
+		"Reply": {
+			text:    "echo hello world --loud",
+			replies: []string{"@alice says hello world! in #general"},
+			uploads: []synthetic.MockUpload{{Name: "echo.txt", ContentType: "text/plain", Content: "hello world!", Comment: "Echoed"}},
+		},
+		"Error": {
+			text:     "fail",
+			replies:  []string{"it failed"},
+			failures: 1,
+		},
+		"Environment": {
+			text:    "getenv",
+			replies: []string{"shared=yes secret="},
+		},
+		"Timeout": {
+			text:     "wait",
+			replies:  []string{"`wait` was stopped: context deadline exceeded"},
+			failures: 1,
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			msg := synthetic.NewMockMessage(tc.text, true)
+			msg.SetUser(synthetic.NewMockUser("U000001", "@alice"))
+			msg.SetConversation(synthetic.NewMockConversation("C000001", "#general"))
+			before := h.Failures()["plugin.helper."+tc.text[:4]]
+
+			h.Dispatch(command.NewCommand(msg))
+
+			if !reflect.DeepEqual(msg.Replies(), tc.replies) {
+				t.Errorf("wrong replies %q should be %q", msg.Replies(), tc.replies)
+			}
+			if len(msg.Uploads()) != len(tc.uploads) || (len(tc.uploads) > 0 && !reflect.DeepEqual(msg.Uploads(), tc.uploads)) {
+				t.Errorf("wrong uploads %v should be %v", msg.Uploads(), tc.uploads)
+			}
+			if failures := h.Failures()["plugin.helper."+tc.text[:4]] - before; failures != tc.failures {
+				t.Errorf("wrong failures %v should be %v", failures, tc.failures)
+			}
+		})
+	}
+}
+
+func TestPluginLineTooLong(t *testing.T) {
+	disableLogs()
+	p, err := Start("helper", os.Args[0], nil, helperArgs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	h := command.NewHandler()
+	h.SetTimeout(time.Second)
+	if err := p.Register(h); err != nil {
+		t.Fatal(err)
+	}
+	msg := synthetic.NewMockMessage("flood", true)
+
+	h.Dispatch(command.NewCommand(msg))
+
+	expected := []string{"the plugin helper exited while running `flood`"}
+	if !reflect.DeepEqual(msg.Replies(), expected) {
+		t.Errorf("wrong replies %q should be %q", msg.Replies(), expected)
+	}
+	select {
+	case <-p.exited:
+	case <-time.After(time.Second):
+		t.Error("the plugin wasn't killed")
+	}
+}
@@ -0,0 +1,197 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ifosch/synthetic/pkg/command"
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+// Types of the Messages in the protocol.
+const (
+	// TypeRegister is the first Message a plugin writes, with the
+	// Commands it provides.
+	TypeRegister = "register"
+	// TypeCommand is written to the plugin to run one of its
+	// commands.
+	TypeCommand = "command"
+	// TypeCancel is written to the plugin when a command it's
+	// running is cancelled or times out.
+	TypeCancel = "cancel"
+	// TypeReply, TypeReact and TypeUnreact are written by the plugin
+	// to reply to the message of a command, and to add and remove
+	// reactions to it.
+	TypeReply   = "reply"
+	TypeReact   = "react"
+	TypeUnreact = "unreact"
+	// TypeUpload is written by the plugin to attach a file to the
+	// thread of the command.
+	TypeUpload = "upload"
+	// TypeDone is written by the plugin once a command finished,
+	// with its Error, if any.
+	TypeDone = "done"
+)
+
+// Message is a line of the JSON Lines protocol spoken with the
+// plugins on their standard input and output. The plugin first writes
+// a TypeRegister Message with its Commands. Then, for every
+// TypeCommand Message it reads, it writes any number of TypeReply,
+// TypeReact, TypeUnreact and TypeUpload Messages, and a final
+// TypeDone one, all of them with the ID of the command. Several
+// commands can run at once. The lines the plugin writes can be up to
+// 16MB, so the files uploaded up to about 12MB, and it's killed if it
+// writes a longer one. Anything the plugin writes to its standard
+// error is logged.
+type Message struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+	// Commands are the commands registered by TypeRegister.
+	Commands []Spec `json:"commands,omitempty"`
+	// Command is the command to run in TypeCommand.
+	Command *Payload `json:"command,omitempty"`
+	// Text is the text of TypeReply, replied in the thread of the
+	// command if Thread is true, or if the command was sent in a
+	// thread, and visible only to the user who sent the command if
+	// Ephemeral is true. It's the comment of the file in TypeUpload.
+	Text      string `json:"text,omitempty"`
+	Thread    bool   `json:"thread,omitempty"`
+	Ephemeral bool   `json:"ephemeral,omitempty"`
+	// Name, ContentType and Content are the file of TypeUpload, like
+	// `build.log` with `text/plain`. Content is encoded in base64.
+	Name        string `json:"name,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Content     []byte `json:"content,omitempty"`
+	// Reaction is the name of the reaction of TypeReact and
+	// TypeUnreact, like `+1`.
+	Reaction string `json:"reaction,omitempty"`
+	// Error is the error of TypeDone, if the command failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Arg is a positional argument of a Spec.
+type Arg struct {
+	Name     string `json:"name"`
+	Required bool   `json:"required,omitempty"`
+	Variadic bool   `json:"variadic,omitempty"`
+}
+
+// Spec is a command registered by a plugin, with the same grammar and
+// documentation as a command.Spec.
+type Spec struct {
+	// Name identifies the command in the plugin.
+	Name        string   `json:"name"`
+	Verb        string   `json:"verb"`
+	Subcommands []string `json:"subcommands,omitempty"`
+	Args        []Arg    `json:"args,omitempty"`
+	Params      []string `json:"params,omitempty"`
+	AnyParams   bool     `json:"any_params,omitempty"`
+	Flags       []string `json:"flags,omitempty"`
+	// Timeout is a duration like `5m`, overriding the default
+	// timeout of the commands.
+	Timeout string `json:"timeout,omitempty"`
+	// Overflow is `split` or `snippet`, overriding how the replies
+	// too long for the chat platform are sent.
+	Overflow string `json:"overflow,omitempty"`
+	// PublicErrors replies the errors of the command to everyone,
+	// instead of only to the user who sent it.
+	PublicErrors bool     `json:"public_errors,omitempty"`
+	Summary      string   `json:"summary,omitempty"`
+	Usage        string   `json:"usage,omitempty"`
+	Examples     []string `json:"examples,omitempty"`
+	Category     string   `json:"category,omitempty"`
+}
+
+// commandSpec returns the command.Spec of the Spec.
+func (s *Spec) commandSpec() (command.Spec, error) {
+	spec := command.Spec{
+		Verb:         s.Verb,
+		Subcommands:  s.Subcommands,
+		Params:       s.Params,
+		AnyParams:    s.AnyParams,
+		Flags:        s.Flags,
+		Summary:      s.Summary,
+		Usage:        s.Usage,
+		Examples:     s.Examples,
+		Category:     s.Category,
+		Overflow:     synthetic.Overflow(s.Overflow),
+		PublicErrors: s.PublicErrors,
+	}
+	if spec.Overflow != "" && spec.Overflow != synthetic.OverflowSplit && spec.Overflow != synthetic.OverflowSnippet {
+		return spec, fmt.Errorf("wrong overflow of `%s`: `%s` isn't `split` nor `snippet`", s.Name, s.Overflow)
+	}
+	for _, arg := range s.Args {
+		spec.Args = append(spec.Args, command.Arg{Name: arg.Name, Required: arg.Required, Variadic: arg.Variadic})
+	}
+	if s.Timeout != "" {
+		timeout, err := time.ParseDuration(s.Timeout)
+		if err != nil {
+			return spec, fmt.Errorf("wrong timeout of `%s`: %w", s.Name, err)
+		}
+		spec.Timeout = timeout
+	}
+	return spec, nil
+}
+
+// User is the user sending a command.
+type User struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// Conversation is the conversation where a command was sent.
+type Conversation struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Payload is a command for a plugin to run.
+type Payload struct {
+	// Name is the name of the command in its Spec.
+	Name         string            `json:"name"`
+	Text         string            `json:"text"`
+	Tokens       []string          `json:"tokens"`
+	Args         map[string]string `json:"args"`
+	Params       map[string]string `json:"params"`
+	Flags        map[string]bool   `json:"flags"`
+	User         User              `json:"user"`
+	Conversation Conversation      `json:"conversation"`
+	MessageID    string            `json:"message_id"`
+	Thread       bool              `json:"thread"`
+	ThreadID     string            `json:"thread_id"`
+}
+
+// newPayload returns the Payload to run `c` as the command `spec`.
+func newPayload(spec *Spec, c *command.Command) *Payload {
+	msg := c.Message()
+	payload := &Payload{
+		Name:   spec.Name,
+		Text:   msg.Text(),
+		Tokens: c.Tokens(),
+		Args:   map[string]string{},
+		Params: c.Params(),
+		Flags:  map[string]bool{},
+		User: User{
+			ID:     msg.User().ID(),
+			Name:   msg.User().Name(),
+			Groups: msg.User().Groups(),
+		},
+		Conversation: Conversation{
+			ID:   msg.Conversation().ID(),
+			Name: msg.Conversation().Name(),
+		},
+		MessageID: msg.ID(),
+		Thread:    msg.Thread(),
+		ThreadID:  msg.ThreadID(),
+	}
+	for _, arg := range spec.Args {
+		if value := c.Arg(arg.Name); value != "" {
+			payload.Args[arg.Name] = value
+		}
+	}
+	for _, flag := range spec.Flags {
+		payload.Flags[flag] = c.Flag(flag)
+	}
+	return payload
+}
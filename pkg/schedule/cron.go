@@ -0,0 +1,165 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearch limits how far in the future Next looks for a matching
+// time, so impossible expressions like `0 0 31 2 *` don't loop
+// forever.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// field describes the range of values of a Cron field.
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// Cron is a parsed cron expression with the usual five fields:
+// minute, hour, day of month, month and day of week. Each field can
+// be `*`, a value, a range like `1-5`, a list like `1,15`, and any of
+// them but values can have a step, like `*/15`. Both 0 and 7 are
+// Sunday in the day of week.
+type Cron struct {
+	expression string
+	values     [5]map[int]bool
+	// Like in cron, when both the day of month and the day of week
+	// are restricted, a day matching any of them matches.
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+// ParseCron parses a cron `expression`.
+func ParseCron(expression string) (*Cron, error) {
+	parts := strings.Fields(expression)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("`%s` is not a valid cron expression: it must have 5 fields, minute, hour, day of month, month and day of week", expression)
+	}
+	c := &Cron{
+		expression:    strings.Join(parts, " "),
+		anyDayOfMonth: parts[2] == "*",
+		anyDayOfWeek:  parts[4] == "*",
+	}
+	for i, part := range parts {
+		values, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("`%s` is not a valid cron expression: %w", expression, err)
+		}
+		c.values[i] = values
+	}
+	if c.values[4][7] {
+		c.values[4][0] = true
+	}
+	return c, nil
+}
+
+// parseField returns the values of `f` in `part`.
+func parseField(part string, f field) (map[int]bool, error) {
+	values := map[int]bool{}
+	for _, item := range strings.Split(part, ",") {
+		step := 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(item[i+1:])
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("wrong step `%s` in the %s", item[i+1:], f.name)
+			}
+			item = item[:i]
+		}
+		first, last := f.min, f.max
+		switch {
+		case item == "*":
+		case strings.Contains(item, "-"):
+			bounds := strings.SplitN(item, "-", 2)
+			var err error
+			if first, err = parseValue(bounds[0], f); err != nil {
+				return nil, err
+			}
+			if last, err = parseValue(bounds[1], f); err != nil {
+				return nil, err
+			}
+			if first > last {
+				return nil, fmt.Errorf("wrong range `%s` in the %s", item, f.name)
+			}
+		default:
+			value, err := parseValue(item, f)
+			if err != nil {
+				return nil, err
+			}
+			first, last = value, value
+			if step > 1 {
+				last = f.max
+			}
+		}
+		for value := first; value <= last; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
+
+// parseValue parses a single value of `f`.
+func parseValue(value string, f field) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("wrong value `%s` in the %s, it must be between %d and %d", value, f.name, f.min, f.max)
+	}
+	return n, nil
+}
+
+// String returns the cron expression.
+func (c *Cron) String() string {
+	return c.expression
+}
+
+// Matches reports whether the minute of `t` matches the expression.
+func (c *Cron) Matches(t time.Time) bool {
+	return c.values[0][t.Minute()] &&
+		c.values[1][t.Hour()] &&
+		c.values[3][int(t.Month())] &&
+		c.matchesDay(t)
+}
+
+// matchesDay reports whether the day of `t` matches the expression.
+func (c *Cron) matchesDay(t time.Time) bool {
+	dayOfMonth := c.values[2][t.Day()]
+	dayOfWeek := c.values[4][int(t.Weekday())]
+	switch {
+	case c.anyDayOfMonth:
+		return dayOfWeek
+	case c.anyDayOfWeek:
+		return dayOfMonth
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+// Next returns the first minute after `t` matching the expression,
+// or the zero time if there's none in the next years.
+func (c *Cron) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+	for next.Before(limit) {
+		switch {
+		case !c.values[3][int(next.Month())] || !c.matchesDay(next):
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+		case !c.values[1][next.Hour()]:
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+		case !c.values[0][next.Minute()]:
+			next = next.Add(time.Minute)
+		default:
+			return next
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,85 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	tt := map[string]struct {
+		expression string
+		valid      bool
+	}{
+		"Every minute":        {"* * * * *", true},
+		"Weekdays":            {"0 9 * * 1-5", true},
+		"Lists and steps":     {"*/15 8,12-18/2 1 */3 7", true},
+		"Extra spaces":        {" 0  9 * * * ", true},
+		"Missing fields":      {"0 9 * *", false},
+		"Out of range":        {"60 * * * *", false},
+		"Wrong range":         {"* 18-8 * * *", false},
+		"Wrong step":          {"*/0 * * * *", false},
+		"Not a number":        {"* * * jan *", false},
+		"Day of week too big": {"* * * * 8", false},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			_, err := ParseCron(tc.expression)
+			if (err == nil) != tc.valid {
+				t.Errorf("wrong error %v parsing `%s` for valid %v", err, tc.expression, tc.valid)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// Friday
+	from := time.Date(2021, time.January, 1, 10, 30, 20, 0, time.UTC)
+	tt := map[string]struct {
+		expression string
+		next       time.Time
+	}{
+		"Every minute": {
+			expression: "* * * * *",
+			next:       time.Date(2021, time.January, 1, 10, 31, 0, 0, time.UTC),
+		},
+		"Every 15 minutes": {
+			expression: "*/15 * * * *",
+			next:       time.Date(2021, time.January, 1, 10, 45, 0, 0, time.UTC),
+		},
+		"Weekdays at 9": {
+			expression: "0 9 * * 1-5",
+			next:       time.Date(2021, time.January, 4, 9, 0, 0, 0, time.UTC),
+		},
+		"Sundays as 7": {
+			expression: "0 0 * * 7",
+			next:       time.Date(2021, time.January, 3, 0, 0, 0, 0, time.UTC),
+		},
+		"Day of month or day of week": {
+			expression: "0 0 2 * 1",
+			next:       time.Date(2021, time.January, 2, 0, 0, 0, 0, time.UTC),
+		},
+		"Next month": {
+			expression: "0 12 1 2 *",
+			next:       time.Date(2021, time.February, 1, 12, 0, 0, 0, time.UTC),
+		},
+		"Impossible": {
+			expression: "0 0 31 2 *",
+			next:       time.Time{},
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			cron, err := ParseCron(tc.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			next := cron.Next(from)
+			if !next.Equal(tc.next) {
+				t.Errorf("wrong next run %v for `%s` should be %v", next, tc.expression, tc.next)
+			}
+			if !next.IsZero() && !cron.Matches(next) {
+				t.Errorf("next run %v doesn't match `%s`", next, tc.expression)
+			}
+		})
+	}
+}
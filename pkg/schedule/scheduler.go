@@ -0,0 +1,299 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ifosch/synthetic/pkg/jsonfile"
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+// Poster posts messages in the conversations, and finds the users
+// running the scheduled commands.
+type Poster interface {
+	Post(conversationID, text string) (synthetic.Message, error)
+	User(id string) (synthetic.User, error)
+}
+
+// Checker returns an error if the user who sent `msg` can't run the
+// command in its text, like when it's unknown or they aren't allowed
+// to run it.
+type Checker func(msg synthetic.Message) error
+
+// Schedule is a command run periodically in a conversation.
+type Schedule struct {
+	ID             int       `json:"id"`
+	Cron           string    `json:"cron"`
+	Command        string    `json:"command"`
+	ConversationID string    `json:"conversation_id"`
+	Conversation   string    `json:"conversation"`
+	Creator        string    `json:"creator"`
+	CreatorID      string    `json:"creator_id"`
+	Created        time.Time `json:"created"`
+
+	cron *Cron
+}
+
+// Scheduler runs the Schedules, sending their commands as unattended
+// messages from the synthetic.SystemUser to a channel, like the ones
+// from the chat, once their creators are checked to be allowed to
+// run them. The Schedules are saved to a file, so they survive
+// restarts.
+type Scheduler struct {
+	sync.Mutex
+	filename  string
+	schedules []*Schedule
+	lastID    int
+	poster    Poster
+	check     Checker
+	messages  chan synthetic.Message
+	now       func() time.Time
+}
+
+// NewScheduler returns a Scheduler with the Schedules saved in
+// `filename`, if it exists. The commands are checked with `check`
+// for their creators when they're scheduled and every time they run,
+// and the messages running them are posted with `poster` and sent to
+// `messages`.
+func NewScheduler(filename string, poster Poster, check Checker, messages chan synthetic.Message) (*Scheduler, error) {
+	s := &Scheduler{
+		filename:  filename,
+		schedules: []*Schedule{},
+		poster:    poster,
+		check:     check,
+		messages:  messages,
+		now:       time.Now,
+	}
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.schedules); err != nil {
+		return nil, fmt.Errorf("error reading the schedules in %s: %w", filename, err)
+	}
+	for _, schedule := range s.schedules {
+		if schedule.cron, err = ParseCron(schedule.Cron); err != nil {
+			return nil, fmt.Errorf("error reading schedule %d in %s: %w", schedule.ID, filename, err)
+		}
+		if schedule.ID > s.lastID {
+			s.lastID = schedule.ID
+		}
+	}
+	return s, nil
+}
+
+// save writes the Schedules to the Scheduler file, replacing it
+// atomically. It must be called with the Scheduler locked.
+func (s *Scheduler) save() error {
+	return jsonfile.Save(s.filename, s.schedules)
+}
+
+// Create adds a Schedule running `command` in the conversation of
+// `msg` following the cron `expression`, on behalf of the user who
+// sent `msg`, and replies to `msg` with its ID and next run. It fails
+// if the Scheduler Checker fails for that user and command.
+func (s *Scheduler) Create(msg synthetic.Message, expression, command string) error {
+	cron, err := ParseCron(strings.Trim(expression, "\"'“”‘’"))
+	if err != nil {
+		return err
+	}
+	if command == "" {
+		return fmt.Errorf("you must specify the command to schedule")
+	}
+	if err := s.check(&message{Message: msg, text: command, user: msg.User()}); err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.lastID++
+	schedule := &Schedule{
+		ID:             s.lastID,
+		Cron:           cron.String(),
+		Command:        command,
+		ConversationID: msg.Conversation().ID(),
+		Conversation:   msg.Conversation().Name(),
+		Creator:        msg.User().Name(),
+		CreatorID:      msg.User().ID(),
+		Created:        s.now(),
+		cron:           cron,
+	}
+	s.schedules = append(s.schedules, schedule)
+	if err := s.save(); err != nil {
+		s.schedules = s.schedules[:len(s.schedules)-1]
+		return fmt.Errorf("error saving the schedule: %w", err)
+	}
+
+	msg.Reply(fmt.Sprintf(
+		"Scheduled `%s` as `#%d`, next run at %s",
+		command,
+		schedule.ID,
+		cron.Next(s.now()).Format("2006-01-02 15:04"),
+	), msg.Thread())
+	return nil
+}
+
+// List replies to `msg` with the list of Schedules in its
+// conversation.
+func (s *Scheduler) List(msg synthetic.Message) {
+	s.Lock()
+	defer s.Unlock()
+	result := ""
+	for _, schedule := range s.schedules {
+		if schedule.ConversationID != msg.Conversation().ID() {
+			continue
+		}
+		result = fmt.Sprintf(
+			"%s- `#%d` `%s` at `%s` by %s, next run at %s\n",
+			result,
+			schedule.ID,
+			schedule.Command,
+			schedule.Cron,
+			schedule.Creator,
+			schedule.cron.Next(s.now()).Format("2006-01-02 15:04"),
+		)
+	}
+	if result == "" {
+		msg.Reply("There are no scheduled commands in this conversation", msg.Thread())
+		return
+	}
+	msg.Reply("The scheduled commands in this conversation are:\n"+result, msg.Thread())
+}
+
+// Delete removes the Schedule with the ID in `id`, with an optional
+// `#` prefix, in the conversation of `msg`, and replies to `msg`.
+func (s *Scheduler) Delete(msg synthetic.Message, id string) error {
+	n, err := strconv.Atoi(strings.TrimPrefix(id, "#"))
+	if err != nil {
+		return fmt.Errorf("`%s` is not a valid schedule ID. Use `list schedules` to get the list of scheduled commands", id)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	for i, schedule := range s.schedules {
+		if schedule.ID != n || schedule.ConversationID != msg.Conversation().ID() {
+			continue
+		}
+		schedules := append([]*Schedule{}, s.schedules[:i]...)
+		s.schedules = append(schedules, s.schedules[i+1:]...)
+		if err := s.save(); err != nil {
+			return fmt.Errorf("error saving the schedules: %w", err)
+		}
+		msg.Reply(fmt.Sprintf("Deleted the schedule `#%d` of `%s`", n, schedule.Command), msg.Thread())
+		return nil
+	}
+	return fmt.Errorf("there's no schedule with ID `%d` in this conversation. Use `list schedules` to get the list of scheduled commands", n)
+}
+
+// Run runs the Schedules when they're due until `ctx` is done. Runs
+// missed while the bot wasn't running are skipped.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		now := s.now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(now)):
+		}
+		s.runDue(ctx, next)
+	}
+}
+
+// runDue runs the Schedules matching the minute of `t` as the
+// synthetic.SystemUser, on behalf of their creators. The ones their
+// creators can't run anymore are skipped, replying why.
+func (s *Scheduler) runDue(ctx context.Context, t time.Time) {
+	s.Lock()
+	due := []*Schedule{}
+	for _, schedule := range s.schedules {
+		if schedule.cron.Matches(t) {
+			due = append(due, schedule)
+		}
+	}
+	s.Unlock()
+
+	for _, schedule := range due {
+		if schedule.CreatorID == "" {
+			log.Printf("Skipping schedule %d in %v, as it has no creator ID. Schedule it again", schedule.ID, schedule.Conversation)
+			continue
+		}
+		creator, err := s.poster.User(schedule.CreatorID)
+		if err != nil {
+			log.Printf("Error getting the creator of schedule %d in %v: %v", schedule.ID, schedule.Conversation, err)
+			continue
+		}
+		msg, err := s.poster.Post(
+			schedule.ConversationID,
+			fmt.Sprintf("Running `%s`, scheduled as `#%d` by %s", schedule.Command, schedule.ID, schedule.Creator),
+		)
+		if err != nil {
+			log.Printf("Error posting schedule %d in %v: %v", schedule.ID, schedule.Conversation, err)
+			continue
+		}
+		if err := s.check(&message{Message: msg, text: schedule.Command, user: creator}); err != nil {
+			log.Printf("Skipping schedule %d in %v: %v", schedule.ID, schedule.Conversation, err)
+			msg.Reply(err.Error(), true)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case s.messages <- &message{Message: msg, text: schedule.Command, user: synthetic.SystemUser{}}:
+		}
+	}
+}
+
+// message is the message posted for a scheduled run, sent by `user`
+// to the bot with the scheduled command as text. It's sent by the
+// synthetic.SystemUser to run the command, and by its creator to check
+// it.
+type message struct {
+	synthetic.Message
+	text string
+	user synthetic.User
+}
+
+// Mention is always true, as the message is a command for the bot.
+func (m *message) Mention() bool {
+	return true
+}
+
+// Text returns the scheduled command.
+func (m *message) Text() string {
+	return m.text
+}
+
+// WithOverflow keeps the scheduled command in the message with
+// `overflow`.
+func (m *message) WithOverflow(overflow synthetic.Overflow) synthetic.Message {
+	return &message{Message: m.Message.WithOverflow(overflow), text: m.text, user: m.user}
+}
+
+// Unattended is always true, as the user who scheduled the command
+// may not follow its replies.
+func (m *message) Unattended() bool {
+	return true
+}
+
+// ReplyEphemeral replies `msg` to everyone, as the user who scheduled
+// the command may not follow its replies.
+func (m *message) ReplyEphemeral(msg string, inThread bool) {
+	m.Reply(msg, inThread)
+}
+
+// User returns the user sending the command.
+func (m *message) User() synthetic.User {
+	return m.user
+}
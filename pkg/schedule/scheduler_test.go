@@ -0,0 +1,217 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+// mockPoster posts MockMessages, keeping them and the conversations
+// and texts posted.
+type mockPoster struct {
+	posted   []string
+	messages []*synthetic.MockMessage
+}
+
+func (p *mockPoster) Post(conversationID, text string) (synthetic.Message, error) {
+	p.posted = append(p.posted, conversationID+": "+text)
+	msg := synthetic.NewMockMessage(text, false)
+	msg.SetConversation(synthetic.NewMockConversation(conversationID, "#general"))
+	p.messages = append(p.messages, msg)
+	return msg, nil
+}
+
+func (p *mockPoster) User(id string) (synthetic.User, error) {
+	return synthetic.NewMockUser(id, "@alice"), nil
+}
+
+// check only allows @alice to schedule commands, and none of them
+// `deploy`.
+func check(msg synthetic.Message) error {
+	if msg.User().Name() != "@alice" || msg.Text() == "deploy production" {
+		return fmt.Errorf("you're not allowed to run `%s`", msg.Text())
+	}
+	if !msg.Mention() || !msg.Unattended() {
+		return fmt.Errorf("`%s` should be checked as an unattended command", msg.Text())
+	}
+	return nil
+}
+
+func newMessage(user, conversationID string) *synthetic.MockMessage {
+	msg := synthetic.NewMockMessage("", true)
+	msg.SetUser(synthetic.NewMockUser("U000001", user))
+	msg.SetConversation(synthetic.NewMockConversation(conversationID, "#general"))
+	return msg
+}
+
+func newScheduler(t *testing.T, filename string, poster Poster, messages chan synthetic.Message) *Scheduler {
+	s, err := NewScheduler(filename, poster, check, messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return time.Date(2021, time.January, 1, 10, 30, 0, 0, time.UTC) }
+	return s
+}
+
+func TestScheduler(t *testing.T) {
+	s := newScheduler(t, filepath.Join(t.TempDir(), "schedules.json"), &mockPoster{}, nil)
+
+	tt := []struct {
+		name         string
+		user         string
+		conversation string
+		run          func(msg synthetic.Message) error
+		reply        string
+		err          bool
+	}{
+		{
+			name:  "Empty list",
+			run:   func(msg synthetic.Message) error { s.List(msg); return nil },
+			reply: "There are no scheduled commands in this conversation",
+		},
+		{
+			name:  "Create",
+			run:   func(msg synthetic.Message) error { return s.Create(msg, "\"0 9 * * 1-5\"", "build nightly-report") },
+			reply: "Scheduled `build nightly-report` as `#1`, next run at 2021-01-04 09:00",
+		},
+		{
+			name:  "Create hourly",
+			run:   func(msg synthetic.Message) error { return s.Create(msg, "0 * * * *", "list pods") },
+			reply: "Scheduled `list pods` as `#2`, next run at 2021-01-01 11:00",
+		},
+		{
+			name:         "Create in another conversation",
+			conversation: "CH00002",
+			run:          func(msg synthetic.Message) error { return s.Create(msg, "0 8 * * *", "list pods") },
+			reply:        "Scheduled `list pods` as `#3`, next run at 2021-01-02 08:00",
+		},
+		{
+			name: "Wrong cron",
+			run:  func(msg synthetic.Message) error { return s.Create(msg, "0 9 * *", "list pods") },
+			err:  true,
+		},
+		{
+			name: "Command not allowed",
+			run:  func(msg synthetic.Message) error { return s.Create(msg, "0 9 * * *", "deploy production") },
+			err:  true,
+		},
+		{
+			name: "User not allowed",
+			user: "@bob",
+			run:  func(msg synthetic.Message) error { return s.Create(msg, "0 9 * * *", "list pods") },
+			err:  true,
+		},
+		{
+			name: "List",
+			run:  func(msg synthetic.Message) error { s.List(msg); return nil },
+			reply: "The scheduled commands in this conversation are:\n" +
+				"- `#1` `build nightly-report` at `0 9 * * 1-5` by @alice, next run at 2021-01-04 09:00\n" +
+				"- `#2` `list pods` at `0 * * * *` by @alice, next run at 2021-01-01 11:00\n",
+		},
+		{
+			name:  "Delete",
+			run:   func(msg synthetic.Message) error { return s.Delete(msg, "#2") },
+			reply: "Deleted the schedule `#2` of `list pods`",
+		},
+		{
+			name: "Delete missing",
+			run:  func(msg synthetic.Message) error { return s.Delete(msg, "2") },
+			err:  true,
+		},
+		{
+			name: "Delete in another conversation",
+			run:  func(msg synthetic.Message) error { return s.Delete(msg, "3") },
+			err:  true,
+		},
+		{
+			name: "Delete wrong ID",
+			run:  func(msg synthetic.Message) error { return s.Delete(msg, "two") },
+			err:  true,
+		},
+	}
+	for _, tc := range tt {
+		msg := newMessage(valueOr(tc.user, "@alice"), valueOr(tc.conversation, "CH00001"))
+		err := tc.run(msg)
+		if (err != nil) != tc.err {
+			t.Errorf("%s: wrong error %v", tc.name, err)
+		}
+		replies := []string{}
+		if tc.reply != "" {
+			replies = []string{tc.reply}
+		}
+		if fmt.Sprint(msg.Replies()) != fmt.Sprint(replies) {
+			t.Errorf("%s: wrong replies %v should be %v", tc.name, msg.Replies(), replies)
+		}
+	}
+}
+
+// valueOr returns `value`, or `otherwise` if it's empty.
+func valueOr(value, otherwise string) string {
+	if value == "" {
+		return otherwise
+	}
+	return value
+}
+
+func TestSchedulerRun(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "schedules.json")
+	poster := &mockPoster{}
+	messages := make(chan synthetic.Message, 10)
+	s := newScheduler(t, filename, poster, messages)
+	if err := s.Create(newMessage("@alice", "CH00001"), "0 9 * * 1-5", "build nightly-report"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The schedules survive restarts
+	s = newScheduler(t, filename, poster, messages)
+	if len(s.schedules) != 1 || s.schedules[0].Command != "build nightly-report" || s.lastID != 1 {
+		t.Fatalf("wrong schedules %v loaded", s.schedules)
+	}
+
+	s.runDue(context.Background(), time.Date(2021, time.January, 1, 9, 0, 0, 0, time.UTC))
+	s.runDue(context.Background(), time.Date(2021, time.January, 2, 9, 0, 0, 0, time.UTC))
+	if len(poster.posted) != 1 || poster.posted[0] != "CH00001: Running `build nightly-report`, scheduled as `#1` by @alice" {
+		t.Fatalf("wrong messages posted %v", poster.posted)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("wrong number of commands sent %v should be 1", len(messages))
+	}
+	msg := <-messages
+	if msg.Text() != "build nightly-report" || !msg.Mention() || !msg.Unattended() {
+		t.Errorf("wrong command `%s` sent with mention %v and unattended %v", msg.Text(), msg.Mention(), msg.Unattended())
+	}
+	if msg.User().ID() != "system" {
+		t.Errorf("wrong user %v should be the system user", msg.User().ID())
+	}
+	if msg.Conversation().ID() != "CH00001" {
+		t.Errorf("wrong conversation %v should be CH00001", msg.Conversation().ID())
+	}
+}
+
+func TestSchedulerRunDenied(t *testing.T) {
+	poster := &mockPoster{}
+	messages := make(chan synthetic.Message, 10)
+	s := newScheduler(t, filepath.Join(t.TempDir(), "schedules.json"), poster, messages)
+	if err := s.Create(newMessage("@alice", "CH00001"), "0 9 * * 1-5", "build nightly-report"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The creators are checked again on every run
+	s.check = func(msg synthetic.Message) error {
+		if msg.User().ID() != "U000001" {
+			t.Errorf("wrong user %v checked instead of the creator U000001", msg.User().ID())
+		}
+		return fmt.Errorf("you're not allowed to run `%s`", msg.Text())
+	}
+	s.runDue(context.Background(), time.Date(2021, time.January, 4, 9, 0, 0, 0, time.UTC))
+	if len(messages) != 0 {
+		t.Errorf("the command of a creator no longer allowed was sent")
+	}
+	if len(poster.posted) != 1 || fmt.Sprint(poster.messages[0].Replies()) != "[you're not allowed to run `build nightly-report`]" {
+		t.Errorf("wrong messages posted %v for a creator no longer allowed", poster.posted)
+	}
+}
@@ -0,0 +1,242 @@
+package script
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ifosch/synthetic/pkg/command"
+	"github.com/ifosch/synthetic/pkg/environ"
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+// CategoryScripts is the category of the Script commands in help.
+const CategoryScripts = "scripts"
+
+const (
+	// flushInterval is how often the output of a script is replied.
+	flushInterval = 2 * time.Second
+	// maxLines is the most lines of output in a reply.
+	maxLines = 30
+)
+
+// Script is a command running an executable, declared in the
+// configuration.
+type Script struct {
+	// Name is the verb of the command.
+	Name string `yaml:"name"`
+	// Run is the executable and its arguments, separated by
+	// spaces. Each of them is a template taking the Args, like
+	// `/opt/scripts/flush.sh {{.env}}`. There's no shell involved,
+	// so every argument given by the users stays a single argument.
+	Run string `yaml:"run"`
+	// Args are the names of the positional arguments of the
+	// command, all of them required.
+	Args []string `yaml:"args"`
+	// AllowedValues restricts the values of some Args.
+	AllowedValues map[string][]string `yaml:"allowed_values"`
+	// Env lists the environment variables given to the
+	// executable, besides PATH and HOME. The rest of the bot
+	// environment, with its credentials, isn't given to it.
+	Env []string `yaml:"env"`
+	// Timeout overrides the default timeout of the commands.
+	Timeout  time.Duration `yaml:"timeout"`
+	Summary  string        `yaml:"summary"`
+	Examples []string      `yaml:"examples"`
+
+	templates []*template.Template
+}
+
+// Validate checks the Script and parses its templates.
+func (s *Script) Validate() error {
+	if s.Name == "" || strings.ContainsAny(s.Name, " \t") {
+		return fmt.Errorf("wrong script name `%s`", s.Name)
+	}
+	fields := strings.Fields(s.Run)
+	if len(fields) == 0 {
+		return fmt.Errorf("the script `%s` has nothing to run", s.Name)
+	}
+	s.templates = []*template.Template{}
+	for _, field := range fields {
+		t, err := template.New(s.Name).Option("missingkey=error").Parse(field)
+		if err != nil {
+			return fmt.Errorf("wrong template in the script `%s`: %w", s.Name, err)
+		}
+		s.templates = append(s.templates, t)
+	}
+	for arg := range s.AllowedValues {
+		if !command.Contains(s.Args, arg) {
+			return fmt.Errorf("the script `%s` has allowed values for the unknown argument `%s`", s.Name, arg)
+		}
+	}
+	return nil
+}
+
+// Spec returns the command.Spec of the Script.
+func (s *Script) Spec() command.Spec {
+	spec := command.Spec{
+		Verb:     s.Name,
+		Timeout:  s.Timeout,
+		Summary:  s.Summary,
+		Examples: s.Examples,
+		Category: CategoryScripts,
+	}
+	for _, arg := range s.Args {
+		spec.Args = append(spec.Args, command.Arg{Name: arg, Required: true})
+	}
+	if spec.Summary == "" {
+		spec.Summary = fmt.Sprintf("Runs `%s`", s.Run)
+	}
+	return spec
+}
+
+// Register validates the Script and registers it in `handler` as
+// `script.<name>`.
+func (s *Script) Register(handler *command.Handler) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	return handler.RegisterCommand("script."+s.Name, s.Spec(), s.Execute)
+}
+
+// arguments returns the executable and arguments to run for the
+// `args` given to the command, once validated.
+func (s *Script) arguments(args map[string]string) ([]string, error) {
+	for arg, allowed := range s.AllowedValues {
+		if !command.Contains(allowed, args[arg]) {
+			return nil, fmt.Errorf("`%s` isn't allowed as %s of `%s`, it must be one of `%s`", args[arg], arg, s.Name, strings.Join(allowed, "`, `"))
+		}
+	}
+	for _, arg := range s.Args {
+		if strings.HasPrefix(args[arg], "-") {
+			return nil, fmt.Errorf("the %s of `%s` can't start with `-`", arg, s.Name)
+		}
+	}
+	result := []string{}
+	for _, t := range s.templates {
+		argument := &bytes.Buffer{}
+		if err := t.Execute(argument, args); err != nil {
+			return nil, fmt.Errorf("error running `%s`: %w", s.Name, err)
+		}
+		result = append(result, argument.String())
+	}
+	return result, nil
+}
+
+// Execute is the ExecutorFunc running the Script for `c`. Its output
+// is replied in the thread as it's written, and its exit code turns
+// into an error when it isn't 0.
+func (s *Script) Execute(c *command.Command) error {
+	args := map[string]string{}
+	for _, arg := range s.Args {
+		args[arg] = c.Arg(arg)
+	}
+	arguments, err := s.arguments(args)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Context()
+	msg := c.Message()
+	cmd := exec.CommandContext(ctx, arguments[0], arguments[1:]...)
+	cmd.Env = environ.Allowed(s.Env)
+	output, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error running `%s`: %w", s.Name, err)
+	}
+	msg.React("gear")
+
+	lines := make(chan string)
+	var readErr error
+	go func() {
+		defer close(lines)
+		readErr = readLines(output, lines)
+	}()
+	stream(ctx, msg, lines)
+	// Waiting closes the output, so the lines left by any process
+	// still writing to it once the script was stopped are dropped.
+	err = cmd.Wait()
+	for range lines {
+	}
+	msg.Unreact("gear")
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		return fmt.Errorf("`%s` was stopped: %w", s.Name, ctx.Err())
+	case errors.As(err, &exitErr):
+		return fmt.Errorf("`%s` failed with exit code %d", s.Name, exitErr.ExitCode())
+	case err != nil:
+		return fmt.Errorf("error running `%s`: %w", s.Name, err)
+	case readErr != nil:
+		return fmt.Errorf("error reading the output of `%s`: %w", s.Name, readErr)
+	}
+	msg.React("heavy_check_mark")
+	return nil
+}
+
+// readLines sends the lines read from `output` to `lines`, however
+// long they are, until it's closed. On errors, it keeps reading and
+// discarding the rest of `output`, so the script doesn't block
+// writing to it, and returns the error.
+func readLines(output io.Reader, lines chan<- string) error {
+	reader := bufio.NewReader(output)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			lines <- strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			_, _ = io.Copy(io.Discard, output)
+			return err
+		}
+	}
+}
+
+// stream replies in the thread of `msg` with the `lines` of output,
+// every flushInterval or maxLines, until `lines` is closed or `ctx` is
+// done.
+func stream(ctx context.Context, msg synthetic.Message, lines chan string) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	buffered := []string{}
+	flush := func() {
+		if len(buffered) == 0 {
+			return
+		}
+		msg.Reply(fmt.Sprintf("```\n%s\n```", strings.Join(buffered, "\n")), true)
+		buffered = []string{}
+	}
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				flush()
+				return
+			}
+			buffered = append(buffered, line)
+			if len(buffered) >= maxLines {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-ctx.Done():
+			flush()
+			return
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package script
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ifosch/synthetic/pkg/command"
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+func disableLogs() {
+	log.SetFlags(0)
+	log.SetOutput(io.Discard)
+}
+
+// writeScript writes an executable shell script with `body` in `dir`.
+func writeScript(t *testing.T, dir, name, body string) string {
+	filename := filepath.Join(dir, name)
+	if err := os.WriteFile(filename, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestValidate(t *testing.T) {
+	tt := map[string]struct {
+		script Script
+		valid  bool
+	}{
+		"Valid": {
+			script: Script{Name: "flush-cache", Run: "/opt/flush.sh {{.env}}", Args: []string{"env"}, AllowedValues: map[string][]string{"env": {"staging"}}},
+			valid:  true,
+		},
+		"No name": {
+			script: Script{Run: "/opt/flush.sh"},
+		},
+		"Nothing to run": {
+			script: Script{Name: "flush-cache"},
+		},
+		"Wrong template": {
+			script: Script{Name: "flush-cache", Run: "/opt/flush.sh {{.env"},
+		},
+		"Unknown argument": {
+			script: Script{Name: "flush-cache", Run: "/opt/flush.sh", AllowedValues: map[string][]string{"env": {"staging"}}},
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			err := tc.script.Validate()
+			if (err == nil) != tc.valid {
+				t.Errorf("wrong validation error %v for valid %v", err, tc.valid)
+			}
+		})
+	}
+}
+
+func TestExecute(t *testing.T) {
+	disableLogs()
+	os.Setenv("SYNTHETIC_SHARED", "yes")
+	os.Setenv("SYNTHETIC_SECRET", "xoxb-secret")
+	defer os.Unsetenv("SYNTHETIC_SHARED")
+	defer os.Unsetenv("SYNTHETIC_SECRET")
+	dir := t.TempDir()
+	tt := map[string]struct {
+		script   Script
+		text     string
+		replies  []string
+		reaction string
+	}{
+		"Output": {
+			script: Script{
+				Name:          "flush-cache",
+				Run:           writeScript(t, dir, "flush.sh", "echo flushing $1\necho done >&2") + " {{.env}}",
+				Args:          []string{"env"},
+				AllowedValues: map[string][]string{"env": {"staging", "production"}},
+			},
+			text:     "flush-cache staging",
+			replies:  []string{"```\nflushing staging\ndone\n```"},
+			reaction: "heavy_check_mark",
+		},
+		"Long line": {
+			script: Script{
+				Name: "dump",
+				Run:  writeScript(t, dir, "dump.sh", "head -c 100000 /dev/zero | tr '\\0' x\necho\necho done"),
+			},
+			text:     "dump",
+			replies:  []string{"```\n" + strings.Repeat("x", 100000) + "\ndone\n```"},
+			reaction: "heavy_check_mark",
+		},
+		"Environment": {
+			script: Script{
+				Name: "env",
+				Run:  writeScript(t, dir, "env.sh", "echo shared=$SYNTHETIC_SHARED secret=$SYNTHETIC_SECRET"),
+				Env:  []string{"SYNTHETIC_SHARED"},
+			},
+			text:     "env",
+			replies:  []string{"```\nshared=yes secret=\n```"},
+			reaction: "heavy_check_mark",
+		},
+		"Single arguments": {
+			script: Script{
+				Name: "greet",
+				Run:  writeScript(t, dir, "greet.sh", "echo $# $1") + " {{.name}}",
+				Args: []string{"name"},
+			},
+			text:     "greet \"Alice; rm -rf /\"",
+			replies:  []string{"```\n1 Alice; rm -rf /\n```"},
+			reaction: "heavy_check_mark",
+		},
+		"Not allowed": {
+			script: Script{
+				Name:          "flush-cache",
+				Run:           writeScript(t, dir, "flush-qa.sh", "echo flushing $1") + " {{.env}}",
+				Args:          []string{"env"},
+				AllowedValues: map[string][]string{"env": {"staging", "production"}},
+			},
+			text:     "flush-cache qa",
+			replies:  []string{"`qa` isn't allowed as env of `flush-cache`, it must be one of `staging`, `production`"},
+			reaction: "boom",
+		},
+		"Option": {
+			script: Script{
+				Name: "greet",
+				Run:  writeScript(t, dir, "greet-option.sh", "echo $1") + " {{.name}}",
+				Args: []string{"name"},
+			},
+			text:     "greet --help",
+			replies:  []string{"unknown flag `--help`. Usage: `greet <name>`"},
+			reaction: "",
+		},
+		"Exit code": {
+			script: Script{
+				Name: "fail",
+				Run:  writeScript(t, dir, "fail.sh", "echo failing\nexit 3"),
+			},
+			text:     "fail",
+			replies:  []string{"```\nfailing\n```", "`fail` failed with exit code 3"},
+			reaction: "boom",
+		},
+		"Timeout": {
+			script: Script{
+				Name:    "wait",
+				Run:     writeScript(t, dir, "wait.sh", "echo waiting\nsleep 10"),
+				Timeout: 500 * time.Millisecond,
+			},
+			text:     "wait",
+			replies:  []string{"```\nwaiting\n```", "`wait` was stopped: context deadline exceeded"},
+			reaction: "boom",
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			h := command.NewHandler()
+			if err := tc.script.Register(h); err != nil {
+				t.Fatal(err)
+			}
+			msg := synthetic.NewMockMessage(tc.text, true)
+
+			h.Dispatch(command.NewCommand(msg))
+
+			if !reflect.DeepEqual(msg.Replies(), tc.replies) {
+				t.Errorf("wrong replies %q should be %q", msg.Replies(), tc.replies)
+			}
+			if reactions := msg.Reactions(); tc.reaction != "" && (len(reactions) == 0 || reactions[len(reactions)-1] != tc.reaction) {
+				t.Errorf("wrong reactions %v should end with %v", reactions, tc.reaction)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package slack
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+// maxFields is the most fields Slack allows in a section block.
+const maxFields = 10
+
+// maxHeader is the most characters Slack allows in a header block.
+const maxHeader = 150
+
+// blocks returns the Block Kit blocks rendering `response`.
+func blocks(response *synthetic.Response) []slack.Block {
+	result := []slack.Block{}
+	if response.Header != "" {
+		result = append(result, slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, header(response.Header), false, false)))
+	}
+	for _, block := range response.Blocks {
+		switch b := block.(type) {
+		case synthetic.Fields:
+			for start := 0; start < len(b); start += maxFields {
+				end := start + maxFields
+				if end > len(b) {
+					end = len(b)
+				}
+				fields := []*slack.TextBlockObject{}
+				for _, field := range b[start:end] {
+					fields = append(fields, markdown(fmt.Sprintf("*%s*\n%s", field.Name, field.Value)))
+				}
+				result = append(result, slack.NewSectionBlock(nil, fields, nil))
+			}
+		case synthetic.Links:
+			links := []string{}
+			for _, link := range b {
+				links = append(links, fmt.Sprintf("<%s|%s>", link.URL, link.Text))
+			}
+			result = append(result, slack.NewSectionBlock(markdown(strings.Join(links, "\n")), nil, nil))
+		case synthetic.Context:
+			result = append(result, slack.NewContextBlock("", markdown(string(b))))
+		default:
+			// Sections, code blocks and tables are Markdown text.
+			result = append(result, slack.NewSectionBlock(markdown(block.PlainText()), nil, nil))
+		}
+	}
+	return result
+}
+
+// markdown returns a text object with the Markdown `text`.
+func markdown(text string) *slack.TextBlockObject {
+	return slack.NewTextBlockObject(slack.MarkdownType, text, false, false)
+}
+
+// header returns `text` cut to fit in a header block, ending with an
+// ellipsis when cut.
+func header(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maxHeader {
+		return text
+	}
+	return string(runes[:maxHeader-1]) + "…"
+}
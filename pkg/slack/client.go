@@ -6,9 +6,16 @@ import (
 
 // IClient is an interface for the chat system's client.
 type IClient interface {
-	GetConversationInfo(string, bool) (*slack.Channel, error)
+	GetConversationInfo(*slack.GetConversationInfoInput) (*slack.Channel, error)
 	GetUserInfo(string) (*slack.User, error)
+	GetUserGroups(...slack.GetUserGroupsOption) ([]slack.UserGroup, error)
 	NewRTM(...slack.RTMOption) *slack.RTM
 	AddReaction(string, slack.ItemRef) error
 	RemoveReaction(string, slack.ItemRef) error
+	PostMessage(string, ...slack.MsgOption) (string, string, error)
+	PostEphemeral(string, string, ...slack.MsgOption) (string, error)
+	UpdateMessage(string, string, ...slack.MsgOption) (string, string, string, error)
+	DeleteMessage(string, string) (string, string, error)
+	UploadFileV2(slack.UploadFileV2Parameters) (*slack.FileSummary, error)
+	DeleteFile(string) error
 }
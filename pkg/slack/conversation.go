@@ -17,7 +17,7 @@ type Conversation struct {
 // NewConversationFromID returns a Conversation object wrapping the
 // channel, group conversation, or direct chat identified by `id`.
 func NewConversationFromID(id string, api IClient) (conversation *Conversation, err error) {
-	conversationInfo, err := api.GetConversationInfo(id, false)
+	conversationInfo, err := api.GetConversationInfo(&slack.GetConversationInfoInput{ChannelID: id})
 	if err != nil {
 		return nil, err
 	}
@@ -32,6 +32,11 @@ func NewConversationFromID(id string, api IClient) (conversation *Conversation,
 	return conversation, nil
 }
 
+// ID returns the Slack ID of the conversation.
+func (c *Conversation) ID() string {
+	return c.slackChannel.ID
+}
+
 // Name returns the name of the conversation.
 func (c *Conversation) Name() string {
 	return c.name
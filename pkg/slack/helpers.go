@@ -1,7 +1,10 @@
 package slack
 
 import (
+	"mime"
+	"path"
 	"strings"
+	"unicode/utf8"
 )
 
 // ReplaceSpace ...
@@ -18,8 +21,13 @@ func replaceSpace(s string) string {
 	return string(result)
 }
 
+// unescaper undoes the escaping of `&`, `<` and `>` in the text of
+// the Slack messages.
+var unescaper = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">")
+
 func cleanText(s string) string {
 	s = replaceSpace(s)
+	s = unescaper.Replace(s)
 	s = strings.TrimSpace(s)
 	return s
 }
@@ -40,3 +48,96 @@ func removeWord(text string, word string) string {
 	}
 	return strings.Join(slice, " ")
 }
+
+// fence opens and closes the code blocks.
+const fence = "```"
+
+// split splits `text` at line boundaries into chunks of up to `max`
+// bytes. Lines longer than that are split too. The code blocks split
+// across chunks are closed and opened again, so they still show as
+// code.
+func split(text string, max int) []string {
+	// Room to open the code block again at the start of a chunk and
+	// to close it at the end.
+	limit := max - 2*len(fence+"\n")
+	chunks := []string{}
+	chunk := ""
+	inCode := false
+	for _, line := range lines(text, limit) {
+		if chunk != "" && len(chunk)+len(line) > max-len("\n"+fence) {
+			chunk = strings.TrimSuffix(chunk, "\n")
+			if inCode {
+				chunk += "\n" + fence
+			}
+			chunks = append(chunks, chunk)
+			chunk = ""
+			if inCode {
+				chunk = fence + "\n"
+			}
+		}
+		chunk += line
+		if strings.Count(line, fence)%2 == 1 {
+			inCode = !inCode
+		}
+	}
+	if chunk = strings.TrimSuffix(chunk, "\n"); chunk != "" {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// lines returns the lines of `text`, ending with their newlines, split
+// into pieces of up to `max` bytes when they're longer.
+func lines(text string, max int) []string {
+	result := []string{}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		for len(line) > max {
+			end := max
+			for end > 0 && !utf8.RuneStart(line[end]) {
+				end--
+			}
+			result = append(result, line[:end])
+			line = line[end:]
+		}
+		if line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// extensions are the file extensions of some content types.
+var extensions = map[string]string{
+	"application/gzip":   ".gz",
+	"application/json":   ".json",
+	"application/pdf":    ".pdf",
+	"application/xml":    ".xml",
+	"application/yaml":   ".yaml",
+	"application/x-yaml": ".yaml",
+	"application/zip":    ".zip",
+	"image/gif":          ".gif",
+	"image/jpeg":         ".jpg",
+	"image/png":          ".png",
+	"text/csv":           ".csv",
+	"text/html":          ".html",
+	"text/markdown":      ".md",
+	"text/plain":         ".txt",
+	"text/xml":           ".xml",
+	"text/yaml":          ".yaml",
+}
+
+// fileName returns `name` with the extension of `contentType`, like
+// `console.txt` for `console` and `text/plain; charset=utf-8`, as
+// Slack takes the type of the files from their names. It returns
+// `name` as is when it already has an extension, or the type is
+// unknown.
+func fileName(name, contentType string) string {
+	if path.Ext(name) != "" {
+		return name
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return name
+	}
+	return name + extensions[mediaType]
+}
@@ -1,6 +1,7 @@
 package slack
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -19,3 +20,81 @@ func TestRemoveWord(t *testing.T) {
 		})
 	}
 }
+
+func TestCleanText(t *testing.T) {
+	tc := map[string][]string{
+		"Spaces":          {" build\u00A0deploy ", "build deploy"},
+		"Escaped symbols": {"build a &amp;&amp; build b &gt; &lt;c&gt;", "build a && build b > <c>"},
+		"Escaped entity":  {"&amp;lt;", "&lt;"},
+	}
+
+	for testID, data := range tc {
+		t.Run(testID, func(t *testing.T) {
+			result := cleanText(data[0])
+			if result != data[1] {
+				t.Logf("%v: Cleaning '%v' returned '%v', but '%v' was expected", testID, data[0], result, data[1])
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tc := map[string]struct {
+		text   string
+		chunks []string
+	}{
+		"Short": {
+			text:   "a short reply",
+			chunks: []string{"a short reply"},
+		},
+		"Lines": {
+			text:   "first line\nsecond line\nthird line\n",
+			chunks: []string{"first line\nsecond line", "third line"},
+		},
+		"Code block": {
+			text:   "pods:\n```\npod-1 Running\npod-2 Running\n```\ndone",
+			chunks: []string{"pods:\n```\npod-1 Running\n```", "```\npod-2 Running\n```\ndone"},
+		},
+		"Long line": {
+			text:   "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+			chunks: []string{"aaaaaaaaaaaaaaaaaaaaaaaaaaaa", "aaaaaaaaaa"},
+		},
+	}
+
+	for testID, data := range tc {
+		t.Run(testID, func(t *testing.T) {
+			result := split(data.text, 36)
+			if !reflect.DeepEqual(result, data.chunks) {
+				t.Logf("%v: Splitting '%v' returned %q, but %q was expected", testID, data.text, result, data.chunks)
+				t.Fail()
+			}
+			for _, chunk := range result {
+				if len(chunk) > 36 {
+					t.Logf("%v: Chunk '%v' is longer than 36", testID, chunk)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tc := map[string][]string{
+		"Known":         {"pods", "application/json", "pods.json"},
+		"Parameters":    {"console", "text/plain; charset=utf-8", "console.txt"},
+		"Has extension": {"console.log", "text/plain", "console.log"},
+		"Unknown":       {"dump", "application/octet-stream", "dump"},
+		"Wrong":         {"dump", "", "dump"},
+	}
+
+	for testID, data := range tc {
+		t.Run(testID, func(t *testing.T) {
+			result := fileName(data[0], data[1])
+			if result != data[2] {
+				t.Logf("%v: The file name of '%v' with '%v' is '%v', but '%v' was expected", testID, data[0], data[1], result, data[2])
+				t.Fail()
+			}
+		})
+	}
+}
@@ -1,11 +1,23 @@
 package slack
 
 import (
+	"fmt"
+	"io"
+	"log"
+
 	"github.com/slack-go/slack"
 
 	"github.com/ifosch/synthetic/pkg/synthetic"
 )
 
+const (
+	// maxLength is the most bytes of a reply posted as a single
+	// message, which is also the limit of the text in the blocks.
+	maxLength = 3000
+	// maxBlocks is the most blocks in a message.
+	maxBlocks = 50
+)
+
 // Message contains all the information about a message the bot was
 // notified about.
 type Message struct {
@@ -17,6 +29,12 @@ type Message struct {
 	user         *User
 	conversation *Conversation
 	text         string
+	overflow     synthetic.Overflow
+}
+
+// ID returns the identifier of the message.
+func (m *Message) ID() string {
+	return fmt.Sprintf("%v/%v", m.event.Channel, m.event.Timestamp)
 }
 
 // Thread is an accessor for Thread.
@@ -24,11 +42,23 @@ func (m *Message) Thread() bool {
 	return m.thread
 }
 
+// ThreadID returns an identifier shared by all the messages in the
+// same thread. A message not in a thread starts its own one.
+func (m *Message) ThreadID() string {
+	return fmt.Sprintf("%v/%v", m.event.Channel, m.threadTimestamp())
+}
+
 // Mention is an accessor for Mention.
 func (m *Message) Mention() bool {
 	return m.mention
 }
 
+// Unattended is false, as the messages are sent by users in the
+// chat.
+func (m *Message) Unattended() bool {
+	return false
+}
+
 // User is an accessor for User.
 func (m *Message) User() synthetic.User {
 	return m.user
@@ -44,18 +74,141 @@ func (m *Message) Text() string {
 	return m.text
 }
 
-// Reply send the `msg` string as a reply to the message, in a thread
-// if `inThread` is true.
-func (m *Message) Reply(msg string, inThread bool) {
-	var message *slack.OutgoingMessage
-	if inThread || m.thread {
-		message = m.chat.rtm.NewOutgoingMessage(msg, m.event.Channel, slack.RTMsgOptionTS(m.event.ThreadTimestamp))
-	} else if m.chat.defaultReplyInThread {
-		message = m.chat.rtm.NewOutgoingMessage(msg, m.event.Channel, slack.RTMsgOptionTS(m.event.Timestamp))
-	} else {
-		message = m.chat.rtm.NewOutgoingMessage(msg, m.event.Channel)
+// Reply posts the `msg` string as a reply to the message, in a thread
+// if `inThread` is true, and returns the Reply to change it later.
+// Replies longer than maxLength are split or uploaded as a snippet,
+// following the overflow of the message.
+func (m *Message) Reply(msg string, inThread bool) synthetic.Reply {
+	if len(msg) <= maxLength {
+		return m.post(inThread, slack.MsgOptionText(msg, false))
+	}
+	if m.overflow == synthetic.OverflowSnippet {
+		return m.upload(msg, inThread)
+	}
+	reply := &Reply{chat: m.chat}
+	for _, chunk := range split(msg, maxLength) {
+		posted := m.post(inThread, slack.MsgOptionText(chunk, false)).(*Reply)
+		if posted.err != nil {
+			reply.err = posted.err
+			break
+		}
+		reply.channel = posted.channel
+		reply.timestamps = append(reply.timestamps, posted.timestamps...)
+	}
+	return reply
+}
+
+// ReplyEphemeral posts the `msg` string as a reply to the message
+// visible only to its user, in a thread if `inThread` is true. Replies
+// longer than maxLength are split, as snippets can't be private.
+func (m *Message) ReplyEphemeral(msg string, inThread bool) {
+	for _, chunk := range split(msg, maxLength) {
+		options := []slack.MsgOption{slack.MsgOptionText(chunk, false)}
+		if m.replyInThread(inThread) {
+			options = append(options, slack.MsgOptionTS(m.threadTimestamp()))
+		}
+		if _, err := m.chat.api.PostEphemeral(m.event.Channel, m.event.User, options...); err != nil {
+			log.Printf("Error replying privately to %v: %v", m.ID(), err)
+			return
+		}
+	}
+}
+
+// ReplyResponse posts `response` as a reply to the message, rendered
+// as Block Kit blocks, with its plain text for the notifications. It's
+// replied as plain text when it's too long for the blocks.
+func (m *Message) ReplyResponse(response *synthetic.Response, inThread bool) synthetic.Reply {
+	text := response.PlainText()
+	blocks := blocks(response)
+	if len(text) > maxLength || len(blocks) > maxBlocks {
+		return m.Reply(text, inThread)
+	}
+	return m.post(inThread, slack.MsgOptionText(text, false), slack.MsgOptionBlocks(blocks...))
+}
+
+// WithOverflow returns a copy of the message replying with `overflow`
+// when the replies are longer than maxLength.
+func (m *Message) WithOverflow(overflow synthetic.Overflow) synthetic.Message {
+	message := *m
+	message.overflow = overflow
+	return &message
+}
+
+// post posts a reply to the message with `options`, in a thread if
+// `inThread` is true.
+func (m *Message) post(inThread bool, options ...slack.MsgOption) synthetic.Reply {
+	if m.replyInThread(inThread) {
+		options = append(options, slack.MsgOptionTS(m.threadTimestamp()))
+	}
+	channel, timestamp, err := m.chat.api.PostMessage(m.event.Channel, options...)
+	if err != nil {
+		log.Printf("Error replying to %v: %v", m.ID(), err)
+	}
+	return &Reply{chat: m.chat, channel: channel, timestamps: []string{timestamp}, err: err}
+}
+
+// upload uploads `msg` as a text snippet replying to the message, in
+// a thread if `inThread` is true.
+func (m *Message) upload(msg string, inThread bool) synthetic.Reply {
+	reply, err := m.uploadFile(slack.UploadFileV2Parameters{
+		Content:  msg,
+		FileSize: len(msg),
+		Filename: "reply.txt",
+	}, inThread)
+	if err != nil {
+		log.Printf("Error uploading the reply to %v: %v", m.ID(), err)
+		return &Reply{chat: m.chat, err: err}
+	}
+	return reply
+}
+
+// Upload uploads the file `name` with the `content` of type
+// `contentType` to the thread of the message, with the `comment`, if
+// not empty. The extension of `contentType` is added to `name` if it
+// has none, as Slack takes the type of the file from it. The whole
+// `content` is read before uploading it, as Slack needs its size.
+func (m *Message) Upload(name, contentType string, content io.Reader, comment string) (synthetic.Reply, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+	return m.uploadFile(slack.UploadFileV2Parameters{
+		Content:        string(data),
+		FileSize:       len(data),
+		Filename:       fileName(name, contentType),
+		Title:          name,
+		InitialComment: comment,
+	}, true)
+}
+
+// uploadFile uploads the file in `params` replying to the message, in
+// a thread if `inThread` is true.
+func (m *Message) uploadFile(params slack.UploadFileV2Parameters, inThread bool) (*Reply, error) {
+	params.Channel = m.event.Channel
+	if m.replyInThread(inThread) {
+		params.ThreadTimestamp = m.threadTimestamp()
+	}
+	file, err := m.chat.api.UploadFileV2(params)
+	if err != nil {
+		return nil, err
+	}
+	return &Reply{chat: m.chat, channel: m.event.Channel, fileID: file.ID}, nil
+}
+
+// replyInThread reports whether the replies to the message go to its
+// thread, when `inThread` is true, the message is already in a thread,
+// or the chat replies in threads by default.
+func (m *Message) replyInThread(inThread bool) bool {
+	return inThread || m.thread || m.chat.defaultReplyInThread
+}
+
+// threadTimestamp returns the timestamp of the thread of the message,
+// which is its own one when it's not in a thread.
+func (m *Message) threadTimestamp() string {
+	if m.event.ThreadTimestamp != "" {
+		return m.event.ThreadTimestamp
 	}
-	m.chat.rtm.SendMessage(message)
+	return m.event.Timestamp
 }
 
 // React adds the `reaction` reaction to the message.
@@ -1,9 +1,15 @@
 package slack
 
 import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/slack-go/slack"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
 )
 
 func messageEvents() map[string]*slack.MessageEvent {
@@ -49,10 +55,9 @@ func messageEvents() map[string]*slack.MessageEvent {
 
 func TestReply(t *testing.T) {
 	client := NewMockClient()
-	rtm := NewMockRTM()
 	chat := &Chat{
 		api:                  client,
-		rtm:                  rtm,
+		rtm:                  NewMockRTM(),
 		defaultReplyInThread: false,
 		botID:                "me",
 	}
@@ -66,35 +71,274 @@ func TestReply(t *testing.T) {
 				t.Fail()
 			}
 			message.Reply("reply", false)
-			if len(rtm.messagesSent) != 1 {
-				t.Logf("I've sent only one message, but %v were detected", len(rtm.messagesSent))
+			if len(client.messagesPosted) != 1 {
+				t.Logf("I've sent only one message, but %v were detected", len(client.messagesPosted))
 				t.Fail()
 			}
-			reply := rtm.messagesSent[0]
+			reply := client.messagesPosted[0]
 			if message.Completed {
-				if reply.Channel != message.conversation.slackChannel.ID {
-					t.Logf("Wrong channel ID used in reply %v should be %v", reply.Channel, message.conversation.slackChannel.ID)
+				if reply.channelID != message.conversation.slackChannel.ID {
+					t.Logf("Wrong channel ID used in reply %v should be %v", reply.channelID, message.conversation.slackChannel.ID)
 					t.Fail()
 				}
-				if reply.Text != "reply" {
-					t.Logf("Wrong text in reply %v should be reply", reply.Text)
+				if reply.values().Get("text") != "reply" {
+					t.Logf("Wrong text in reply %v should be reply", reply.values().Get("text"))
 					t.Fail()
 				}
-				if reply.ThreadTimestamp != message.event.ThreadTimestamp {
-					t.Logf("Wrong timestamp in reply %v should be %v", reply.ThreadTimestamp, message.event.ThreadTimestamp)
+				if reply.values().Get("thread_ts") != message.event.ThreadTimestamp {
+					t.Logf("Wrong timestamp in reply %v should be %v", reply.values().Get("thread_ts"), message.event.ThreadTimestamp)
 					t.Fail()
 				}
 			} else {
-				if reply.Channel != "" {
+				if reply.channelID != "" {
 					t.Logf("Incomplete message should have a nil reply but got %v", reply)
 					t.Fail()
 				}
 			}
-			rtm.reset()
+			client.reset()
+		})
+	}
+}
+
+func TestReplyInThread(t *testing.T) {
+	client := NewMockClient()
+	chat := NewChat(client, false, "me")
+	message, err := chat.ReadMessage(messageEvents()["empty message no thread"])
+	if err != nil {
+		t.Fatalf("ReadMessage errored: %v", err)
+	}
+	message.event.Timestamp = "165783949999"
+
+	message.Reply("reply", true)
+
+	if len(client.messagesPosted) != 1 || client.messagesPosted[0].values().Get("thread_ts") != "165783949999" {
+		t.Errorf("Wrong messages posted %v should start a thread in 165783949999", client.messagesPosted)
+	}
+}
+
+func TestEditDeleteReply(t *testing.T) {
+	client := NewMockClient()
+	chat := NewChat(client, false, "me")
+	message, err := chat.ReadMessage(messageEvents()["empty message in thread"])
+	if err != nil {
+		t.Fatalf("ReadMessage errored: %v", err)
+	}
+
+	reply := message.Reply("Queued", false)
+	if err := reply.Edit("Building"); err != nil {
+		t.Fatalf("Edit errored: %v", err)
+	}
+	if err := reply.Delete(); err != nil {
+		t.Fatalf("Delete errored: %v", err)
+	}
+
+	if len(client.messagesUpdated) != 1 {
+		t.Fatalf("Wrong messages updated %v", client.messagesUpdated)
+	}
+	updated := client.messagesUpdated[0]
+	if updated.channelID != "CH00001" || updated.timestamp != "1.000" || updated.values().Get("text") != "Building" {
+		t.Errorf("Wrong update of %v/%v with %v", updated.channelID, updated.timestamp, updated.values())
+	}
+	if len(client.messagesDeleted) != 1 || client.messagesDeleted[0].channelID != "CH00001" || client.messagesDeleted[0].timestamp != "1.000" {
+		t.Errorf("Wrong messages deleted %v", client.messagesDeleted)
+	}
+}
+
+func TestReplyResponse(t *testing.T) {
+	client := NewMockClient()
+	chat := NewChat(client, false, "me")
+	message, err := chat.ReadMessage(messageEvents()["empty message no thread"])
+	if err != nil {
+		t.Fatalf("ReadMessage errored: %v", err)
+	}
+	fields := synthetic.Fields{}
+	for i := 0; i < 11; i++ {
+		fields = append(fields, synthetic.Field{Name: fmt.Sprintf("F%d", i), Value: "x"})
+	}
+	response := &synthetic.Response{
+		Header: "Pods",
+		Blocks: []synthetic.Block{
+			synthetic.Section("Some *pods*"),
+			fields,
+			&synthetic.Table{Columns: []string{"Pod", "Status"}, Rows: [][]string{{"api-1", "Running"}}},
+			synthetic.Links{{Text: "Dashboard", URL: "https://example.com"}},
+			synthetic.Context("2 pods"),
+		},
+	}
+
+	message.ReplyResponse(response, false)
+
+	if len(client.messagesPosted) != 1 {
+		t.Fatalf("Wrong messages posted %v", client.messagesPosted)
+	}
+	values := client.messagesPosted[0].values()
+	if values.Get("text") != response.PlainText() {
+		t.Errorf("Wrong text %v should be %v", values.Get("text"), response.PlainText())
+	}
+	blocks := []map[string]interface{}{}
+	if err := json.Unmarshal([]byte(values.Get("blocks")), &blocks); err != nil {
+		t.Fatalf("Wrong blocks %v: %v", values.Get("blocks"), err)
+	}
+	types := []string{}
+	for _, block := range blocks {
+		types = append(types, block["type"].(string))
+	}
+	expected := []string{"header", "section", "section", "section", "section", "section", "context"}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("Wrong blocks %v should be %v", types, expected)
+	}
+	table := blocks[4]["text"].(map[string]interface{})["text"]
+	if table != "```\nPod    Status\napi-1  Running\n```" {
+		t.Errorf("Wrong table %q", table)
+	}
+	links := blocks[5]["text"].(map[string]interface{})["text"]
+	if links != "<https://example.com|Dashboard>" {
+		t.Errorf("Wrong links %q", links)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	tt := map[string]struct {
+		text     string
+		expected string
+	}{
+		"Short": {
+			text:     "Pods",
+			expected: "Pods",
+		},
+		"Limit": {
+			text:     strings.Repeat("a", maxHeader),
+			expected: strings.Repeat("a", maxHeader),
+		},
+		"Long": {
+			text:     strings.Repeat("a", maxHeader+1),
+			expected: strings.Repeat("a", maxHeader-1) + "…",
+		},
+		"Long multibyte": {
+			text:     strings.Repeat("é", maxHeader+1),
+			expected: strings.Repeat("é", maxHeader-1) + "…",
+		},
+	}
+
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			if result := header(tc.text); result != tc.expected {
+				t.Errorf("Wrong header %q should be %q", result, tc.expected)
+			}
 		})
 	}
 }
 
+// overflowMessage returns a MockClient, a message in a thread read
+// with it, and a reply too long for a single message.
+func overflowMessage(t *testing.T) (*MockClient, *Message, string) {
+	client := NewMockClient()
+	chat := NewChat(client, false, "me")
+	message, err := chat.ReadMessage(messageEvents()["empty message in thread"])
+	if err != nil {
+		t.Fatalf("ReadMessage errored: %v", err)
+	}
+	return client, message, strings.Repeat("pod-1 Running\n", 500)
+}
+
+func TestReplyOverflowSplit(t *testing.T) {
+	client, message, long := overflowMessage(t)
+
+	reply := message.Reply(long, false)
+
+	if len(client.messagesPosted) != 3 {
+		t.Fatalf("Wrong number of messages posted %v should be 3", len(client.messagesPosted))
+	}
+	for _, posted := range client.messagesPosted {
+		if len(posted.values().Get("text")) > maxLength {
+			t.Errorf("Wrong message posted with %v characters", len(posted.values().Get("text")))
+		}
+	}
+	if err := reply.Edit("Done"); err != nil {
+		t.Fatalf("Edit errored: %v", err)
+	}
+	if len(client.messagesUpdated) != 1 || len(client.messagesDeleted) != 2 {
+		t.Errorf("Wrong messages updated %v and deleted %v", client.messagesUpdated, client.messagesDeleted)
+	}
+}
+
+func TestReplyOverflowSnippet(t *testing.T) {
+	client, message, long := overflowMessage(t)
+
+	reply := message.WithOverflow(synthetic.OverflowSnippet).Reply(long, false)
+
+	if len(client.messagesPosted) != 0 || len(client.filesUploaded) != 1 {
+		t.Fatalf("Wrong messages posted %v and files uploaded %v", client.messagesPosted, client.filesUploaded)
+	}
+	uploaded := client.filesUploaded[0]
+	if uploaded.Content != long || uploaded.FileSize != len(long) || uploaded.Channel != "CH00001" || uploaded.ThreadTimestamp != "165783949832" {
+		t.Errorf("Wrong file uploaded in %v/%v", uploaded.Channel, uploaded.ThreadTimestamp)
+	}
+	if err := reply.Edit("Done"); err == nil {
+		t.Errorf("The snippet was edited")
+	}
+	if err := reply.Delete(); err != nil || len(client.filesDeleted) != 1 || client.filesDeleted[0] != "F000001" {
+		t.Errorf("Wrong files deleted %v: %v", client.filesDeleted, err)
+	}
+}
+
+func TestUpload(t *testing.T) {
+	client := NewMockClient()
+	chat := NewChat(client, false, "me")
+	message, err := chat.ReadMessage(messageEvents()["empty message no thread"])
+	if err != nil {
+		t.Fatalf("ReadMessage errored: %v", err)
+	}
+	message.event.Timestamp = "165783949999"
+
+	reply, err := message.Upload("console.log", "text/plain; charset=utf-8", strings.NewReader("Started\nFinished"), "Console output of `deploy`")
+
+	if err != nil {
+		t.Fatalf("Upload errored: %v", err)
+	}
+	if len(client.filesUploaded) != 1 {
+		t.Fatalf("Wrong files uploaded %v", client.filesUploaded)
+	}
+	uploaded := client.filesUploaded[0]
+	expected := slack.UploadFileV2Parameters{
+		Content:         "Started\nFinished",
+		FileSize:        16,
+		Filename:        "console.log",
+		Title:           "console.log",
+		InitialComment:  "Console output of `deploy`",
+		Channel:         "CH00001",
+		ThreadTimestamp: "165783949999",
+	}
+	if !reflect.DeepEqual(uploaded, expected) {
+		t.Errorf("Wrong file uploaded %+v should be %+v", uploaded, expected)
+	}
+	if err := reply.Delete(); err != nil || len(client.filesDeleted) != 1 {
+		t.Errorf("Wrong files deleted %v: %v", client.filesDeleted, err)
+	}
+}
+
+func TestReplyEphemeral(t *testing.T) {
+	client := NewMockClient()
+	chat := NewChat(client, false, "me")
+	message, err := chat.ReadMessage(messageEvents()["empty message in thread"])
+	if err != nil {
+		t.Fatalf("ReadMessage errored: %v", err)
+	}
+
+	message.ReplyEphemeral("the job `x` doesn't exist", false)
+
+	if len(client.messagesPosted) != 0 || len(client.messagesPrivate) != 1 {
+		t.Fatalf("Wrong messages posted %v and privately %v", client.messagesPosted, client.messagesPrivate)
+	}
+	reply := client.messagesPrivate[0]
+	if reply.channelID != "CH00001" || reply.userID != "U000001" {
+		t.Errorf("Wrong private reply in %v to %v", reply.channelID, reply.userID)
+	}
+	if reply.values().Get("text") != "the job `x` doesn't exist" || reply.values().Get("thread_ts") != "165783949832" {
+		t.Errorf("Wrong private reply %v", reply.values())
+	}
+}
+
 func TestReactUnreact(t *testing.T) {
 	client := NewMockClient()
 	chat := NewChat(client, false, "me")
@@ -141,3 +385,28 @@ func TestReactUnreact(t *testing.T) {
 		})
 	}
 }
+
+func TestThreadID(t *testing.T) {
+	tc := map[string]struct {
+		event    *slack.MessageEvent
+		threadID string
+	}{
+		"Message starting a thread": {
+			event:    &slack.MessageEvent{Msg: slack.Msg{Channel: "CH00001", Timestamp: "1000.1"}},
+			threadID: "CH00001/1000.1",
+		},
+		"Message in a thread": {
+			event:    &slack.MessageEvent{Msg: slack.Msg{Channel: "CH00001", Timestamp: "1000.2", ThreadTimestamp: "1000.1"}},
+			threadID: "CH00001/1000.1",
+		},
+	}
+	for testID, data := range tc {
+		t.Run(testID, func(t *testing.T) {
+			message := &Message{event: data.event}
+			if message.ThreadID() != data.threadID {
+				t.Logf("Wrong thread ID %v should be %v", message.ThreadID(), data.threadID)
+				t.Fail()
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package slack
 
 import (
+	"fmt"
+	"net/url"
+
 	"github.com/slack-go/slack"
 )
 
@@ -13,13 +16,36 @@ type reactionData struct {
 type MockClient struct {
 	channels         map[string]*slack.Channel
 	users            map[string]*slack.User
+	userGroups       []slack.UserGroup
+	userGroupsErr    error
+	groupsRequested  int
 	reactionsAdded   []reactionData
 	reactionsRemoved []reactionData
+	messagesPosted   []postData
+	messagesPrivate  []postData
+	messagesUpdated  []postData
+	messagesDeleted  []postData
+	filesUploaded    []slack.UploadFileV2Parameters
+	filesDeleted     []string
+}
+
+type postData struct {
+	channelID string
+	userID    string
+	timestamp string
+	options   []slack.MsgOption
 }
 
-// GetConversationInfo returns the channel information for `id`.
-func (c *MockClient) GetConversationInfo(id string, includeLocale bool) (channel *slack.Channel, err error) {
-	return c.channels[id], nil
+// values returns the parameters of the request sending the message.
+func (p postData) values() url.Values {
+	_, values, _ := slack.UnsafeApplyMsgOptions("", p.channelID, "", p.options...)
+	return values
+}
+
+// GetConversationInfo returns the channel information for the
+// channel in `input`.
+func (c *MockClient) GetConversationInfo(input *slack.GetConversationInfoInput) (channel *slack.Channel, err error) {
+	return c.channels[input.ChannelID], nil
 }
 
 // GetUserInfo returns the user information for `id`.
@@ -27,6 +53,16 @@ func (c *MockClient) GetUserInfo(id string) (*slack.User, error) {
 	return c.users[id], nil
 }
 
+// GetUserGroups returns the user groups, including their members, or
+// the error set to fail, counting the requests.
+func (c *MockClient) GetUserGroups(options ...slack.GetUserGroupsOption) ([]slack.UserGroup, error) {
+	c.groupsRequested++
+	if c.userGroupsErr != nil {
+		return nil, c.userGroupsErr
+	}
+	return c.userGroups, nil
+}
+
 // NewRTM returns a null Slack RTM.
 func (c *MockClient) NewRTM(options ...slack.RTMOption) *slack.RTM {
 	return nil
@@ -50,6 +86,63 @@ func (c *MockClient) RemoveReaction(reaction string, item slack.ItemRef) error {
 	return nil
 }
 
+// PostMessage registers the message posted in `channelID` for
+// validation, returning a timestamp based on the number of messages
+// posted.
+func (c *MockClient) PostMessage(channelID string, options ...slack.MsgOption) (string, string, error) {
+	c.messagesPosted = append(c.messagesPosted, postData{
+		channelID: channelID,
+		options:   options,
+	})
+	return channelID, fmt.Sprintf("%d.000", len(c.messagesPosted)), nil
+}
+
+// PostEphemeral registers the message posted in `channelID` for
+// `userID` only, for validation.
+func (c *MockClient) PostEphemeral(channelID, userID string, options ...slack.MsgOption) (string, error) {
+	c.messagesPrivate = append(c.messagesPrivate, postData{
+		channelID: channelID,
+		userID:    userID,
+		options:   options,
+	})
+	return fmt.Sprintf("%d.000", len(c.messagesPrivate)), nil
+}
+
+// UpdateMessage registers the update of the message with `timestamp`
+// in `channelID` for validation.
+func (c *MockClient) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+	c.messagesUpdated = append(c.messagesUpdated, postData{
+		channelID: channelID,
+		timestamp: timestamp,
+		options:   options,
+	})
+	return channelID, timestamp, "", nil
+}
+
+// DeleteMessage registers the removal of the message with `timestamp`
+// in `channelID` for validation.
+func (c *MockClient) DeleteMessage(channelID, timestamp string) (string, string, error) {
+	c.messagesDeleted = append(c.messagesDeleted, postData{
+		channelID: channelID,
+		timestamp: timestamp,
+	})
+	return channelID, timestamp, nil
+}
+
+// UploadFileV2 registers the file uploaded with `params` for
+// validation, returning an ID based on the number of files uploaded.
+func (c *MockClient) UploadFileV2(params slack.UploadFileV2Parameters) (*slack.FileSummary, error) {
+	c.filesUploaded = append(c.filesUploaded, params)
+	return &slack.FileSummary{ID: fmt.Sprintf("F%06d", len(c.filesUploaded)), Title: params.Title}, nil
+}
+
+// DeleteFile registers the removal of the file `fileID` for
+// validation.
+func (c *MockClient) DeleteFile(fileID string) error {
+	c.filesDeleted = append(c.filesDeleted, fileID)
+	return nil
+}
+
 func (c *MockClient) reset() {
 	c.channels = map[string]*slack.Channel{
 		"CH00001": {
@@ -91,8 +184,26 @@ func (c *MockClient) reset() {
 			Name: "username",
 		},
 	}
+	c.userGroups = []slack.UserGroup{
+		{
+			ID:     "S000001",
+			Handle: "sre",
+			Users:  []string{"U000001"},
+		},
+		{
+			ID:     "S000002",
+			Handle: "developers",
+			Users:  []string{"U000002"},
+		},
+	}
 	c.reactionsAdded = []reactionData{}
 	c.reactionsRemoved = []reactionData{}
+	c.messagesPosted = []postData{}
+	c.messagesPrivate = []postData{}
+	c.messagesUpdated = []postData{}
+	c.messagesDeleted = []postData{}
+	c.filesUploaded = []slack.UploadFileV2Parameters{}
+	c.filesDeleted = []string{}
 }
 
 // NewMockClient creates a new MockClient.
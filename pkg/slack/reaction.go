@@ -0,0 +1,31 @@
+package slack
+
+import (
+	"fmt"
+
+	"github.com/slack-go/slack"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
+)
+
+// Reaction contains all the information about a reaction the bot was
+// notified about.
+type Reaction struct {
+	event *slack.ReactionAddedEvent
+	user  *User
+}
+
+// Name returns the name of the emoji, like `+1`.
+func (r *Reaction) Name() string {
+	return r.event.Reaction
+}
+
+// MessageID returns the ID of the message the reaction was added to.
+func (r *Reaction) MessageID() string {
+	return fmt.Sprintf("%v/%v", r.event.Item.Channel, r.event.Item.Timestamp)
+}
+
+// User is an accessor for User.
+func (r *Reaction) User() synthetic.User {
+	return r.user
+}
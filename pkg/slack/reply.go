@@ -0,0 +1,62 @@
+package slack
+
+import (
+	"fmt"
+
+	"github.com/slack-go/slack"
+)
+
+// Reply is a message posted by the bot replying to a Message. It's
+// several messages when the reply was split, and a snippet when it was
+// uploaded.
+type Reply struct {
+	chat       *Chat
+	channel    string
+	timestamps []string
+	fileID     string
+	// err is the error posting the message, returned by any change
+	// to it.
+	err error
+}
+
+// Edit replaces the text of the reply with `msg`, removing its blocks
+// if it was a Response. A reply split in several messages is edited
+// into the first one, deleting the others. Snippets can't be edited.
+func (r *Reply) Edit(msg string) error {
+	switch {
+	case r.err != nil:
+		return r.err
+	case r.fileID != "":
+		return fmt.Errorf("the reply was uploaded as a snippet, so it can't be edited")
+	case len(msg) > maxLength:
+		return fmt.Errorf("the reply can't be edited with %d characters, as the most are %d", len(msg), maxLength)
+	}
+	_, _, _, err := r.chat.api.UpdateMessage(r.channel, r.timestamps[0], slack.MsgOptionText(msg, false), slack.MsgOptionBlocks([]slack.Block{}...))
+	if err != nil {
+		return err
+	}
+	for len(r.timestamps) > 1 {
+		if _, _, err := r.chat.api.DeleteMessage(r.channel, r.timestamps[1]); err != nil {
+			return err
+		}
+		r.timestamps = append(r.timestamps[:1], r.timestamps[2:]...)
+	}
+	return nil
+}
+
+// Delete removes the reply.
+func (r *Reply) Delete() error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.fileID != "" {
+		return r.chat.api.DeleteFile(r.fileID)
+	}
+	for len(r.timestamps) > 0 {
+		if _, _, err := r.chat.api.DeleteMessage(r.channel, r.timestamps[0]); err != nil {
+			return err
+		}
+		r.timestamps = r.timestamps[1:]
+	}
+	return nil
+}
@@ -17,7 +17,9 @@ type Chat struct {
 	rtm                  IRTM
 	defaultReplyInThread bool
 	botID                string
+	groups               *userGroups
 	MessageChannel       chan (synthetic.Message)
+	ReactionChannel      chan (synthetic.Reaction)
 }
 
 // NewChat is the constructor for the Chat object.
@@ -27,7 +29,9 @@ func NewChat(api IClient, defaultReplyInThread bool, botID string) *Chat {
 		rtm:                  api.NewRTM(),
 		defaultReplyInThread: defaultReplyInThread,
 		botID:                botID,
+		groups:               newUserGroups(api),
 		MessageChannel:       make(chan synthetic.Message),
+		ReactionChannel:      make(chan synthetic.Reaction),
 	}
 }
 
@@ -50,14 +54,9 @@ func (c *Chat) Dispatch(msg *Message) {
 func (c *Chat) Process(msg slack.RTMEvent) {
 	switch ev := msg.Data.(type) {
 	case *slack.MessageEvent:
-		msg, err := c.ReadMessage(ev)
-		if err != nil {
-			log.Printf("Error %v processing message %v", err, ev)
-			return
-		}
-		if msg.Completed {
-			c.Dispatch(msg)
-		}
+		c.processMessage(ev)
+	case *slack.ReactionAddedEvent:
+		c.processReaction(ev)
 	case *slack.ConnectingEvent:
 		log.Printf("Trying to connect to Slack: Attempt %v of %v", ev.Attempt, ev.ConnectionCount)
 	case *slack.ConnectedEvent:
@@ -86,6 +85,33 @@ func (c *Chat) Process(msg slack.RTMEvent) {
 	}
 }
 
+// processMessage dispatches the message of `ev`, once completed.
+func (c *Chat) processMessage(ev *slack.MessageEvent) {
+	msg, err := c.ReadMessage(ev)
+	if err != nil {
+		log.Printf("Error %v processing message %v", err, ev)
+		return
+	}
+	if msg.Completed {
+		c.Dispatch(msg)
+	}
+}
+
+// processReaction sends the reaction of `ev` to the ReactionChannel,
+// unless the bot added it.
+func (c *Chat) processReaction(ev *slack.ReactionAddedEvent) {
+	// The bot's own reactions are just feedback to the users.
+	if ev.User == c.botID {
+		return
+	}
+	reaction, err := c.ReadReaction(ev)
+	if err != nil {
+		log.Printf("Error %v processing reaction %v", err, ev)
+		return
+	}
+	c.ReactionChannel <- reaction
+}
+
 // ReadMessage generates the `Message` from a message event.
 func (c *Chat) ReadMessage(event *slack.MessageEvent) (*Message, error) {
 	thread := false
@@ -104,7 +130,7 @@ func (c *Chat) ReadMessage(event *slack.MessageEvent) (*Message, error) {
 	if event.ThreadTimestamp != "" {
 		thread = true
 	}
-	user, err := NewUserFromID(event.User, c.api)
+	user, err := c.User(event.User)
 	if err != nil {
 		return nil, err
 	}
@@ -129,3 +155,59 @@ func (c *Chat) ReadMessage(event *slack.MessageEvent) (*Message, error) {
 		text:         cleanText(text),
 	}, nil
 }
+
+// ReadReaction generates the `Reaction` from a reaction added event.
+func (c *Chat) ReadReaction(event *slack.ReactionAddedEvent) (*Reaction, error) {
+	user, err := c.User(event.User)
+	if err != nil {
+		return nil, err
+	}
+	return &Reaction{
+		event: event,
+		user:  user,
+	}, nil
+}
+
+// User returns the User identified by `id`, with the user groups
+// cached by the Chat.
+func (c *Chat) User(id string) (*User, error) {
+	user, err := NewUserFromID(id, c.api)
+	if err != nil {
+		return nil, err
+	}
+	user.groups = c.groups
+	return user, nil
+}
+
+// Post posts `text` in the conversation identified by
+// `conversationID`, and returns the Message posted by the bot.
+func (c *Chat) Post(conversationID, text string) (*Message, error) {
+	channel, timestamp, err := c.api.PostMessage(conversationID, slack.MsgOptionText(text, false))
+	if err != nil {
+		return nil, err
+	}
+	event := &slack.MessageEvent{
+		Msg: slack.Msg{
+			Channel:   channel,
+			Timestamp: timestamp,
+			User:      c.botID,
+			Text:      text,
+		},
+	}
+	user, err := c.User(c.botID)
+	if err != nil {
+		return nil, err
+	}
+	conversation, err := NewConversationFromID(channel, c.api)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{
+		event:        event,
+		chat:         c,
+		Completed:    true,
+		user:         user,
+		conversation: conversation,
+		text:         text,
+	}, nil
+}
@@ -8,6 +8,8 @@ import (
 	"testing"
 
 	s "github.com/slack-go/slack"
+
+	"github.com/ifosch/synthetic/pkg/synthetic"
 )
 
 func disableLogs() {
@@ -201,3 +203,73 @@ func TestReadMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessReaction(t *testing.T) {
+	disableLogs()
+	client := NewMockClient()
+	c := NewChat(client, false, "me")
+	c.ReactionChannel = make(chan synthetic.Reaction, 2)
+
+	c.Process(s.RTMEvent{
+		Data: &s.ReactionAddedEvent{
+			User:     "me",
+			Reaction: "hourglass_flowing_sand",
+		},
+	})
+	reactionEvent := &s.ReactionAddedEvent{
+		User:     "U000001",
+		Reaction: "+1",
+	}
+	reactionEvent.Item.Channel = "CH00001"
+	reactionEvent.Item.Timestamp = "1000.1"
+	c.Process(s.RTMEvent{
+		Data: reactionEvent,
+	})
+
+	if len(c.ReactionChannel) != 1 {
+		t.Fatalf("Wrong number of processed reactions %v should be 1", len(c.ReactionChannel))
+	}
+	reaction := <-c.ReactionChannel
+	if reaction.Name() != "+1" {
+		t.Logf("Wrong reaction %v should be +1", reaction.Name())
+		t.Fail()
+	}
+	if reaction.MessageID() != "CH00001/1000.1" {
+		t.Logf("Wrong message ID %v should be CH00001/1000.1", reaction.MessageID())
+		t.Fail()
+	}
+	if reaction.User().Name() != "@username" {
+		t.Logf("Wrong user %v should be @username", reaction.User().Name())
+		t.Fail()
+	}
+}
+
+func TestPost(t *testing.T) {
+	client := NewMockClient()
+	c := NewChat(client, false, "U000001")
+
+	msg, err := c.Post("CH00001", "Good morning")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.messagesPosted) != 1 || client.messagesPosted[0].channelID != "CH00001" {
+		t.Fatalf("Wrong messages posted %v", client.messagesPosted)
+	}
+	if msg.ID() != "CH00001/1.000" {
+		t.Logf("Wrong message ID %v should be CH00001/1.000", msg.ID())
+		t.Fail()
+	}
+	if msg.Text() != "Good morning" {
+		t.Logf("Wrong text %v should be Good morning", msg.Text())
+		t.Fail()
+	}
+	if msg.User().ID() != "U000001" {
+		t.Logf("Wrong user %v should be the bot", msg.User().ID())
+		t.Fail()
+	}
+	if msg.Conversation().ID() != "CH00001" {
+		t.Logf("Wrong conversation %v should be CH00001", msg.Conversation().ID())
+		t.Fail()
+	}
+}
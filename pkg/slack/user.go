@@ -2,15 +2,27 @@ package slack
 
 import (
 	"fmt"
+	"log"
+	"sync"
+	"time"
 
 	"github.com/slack-go/slack"
 )
 
+// groupsTTL is how long the members of the user groups are cached
+// before requesting them to Slack again.
+const groupsTTL = 5 * time.Minute
+
+// groupsRetry is how long to wait before requesting the members of
+// the user groups to Slack again after failing to.
+const groupsRetry = time.Minute
+
 // User is a weapper over slack-go's User object. It provides some
 // utility methods over the User information.
 type User struct {
 	slackUser *slack.User
 	name      string
+	groups    *userGroups
 }
 
 // NewUserFromID returns a User object wrapping the user identified by
@@ -20,11 +32,76 @@ func NewUserFromID(id string, api IClient) (user *User, err error) {
 	if err != nil {
 		return nil, err
 	}
-	user = &User{userInfo, fmt.Sprintf("@%v", userInfo.Name)}
+	user = &User{
+		slackUser: userInfo,
+		name:      fmt.Sprintf("@%v", userInfo.Name),
+		groups:    newUserGroups(api),
+	}
 	return user, err
 }
 
+// ID returns the Slack ID of the user.
+func (u *User) ID() string {
+	return u.slackUser.ID
+}
+
 // Name returns the name of the user.
 func (u *User) Name() string {
 	return u.name
 }
+
+// Groups returns the handles of the Slack user groups the user
+// belongs to, like `@sre`. The users read by a Chat share the groups
+// cached by it.
+func (u *User) Groups() []string {
+	return u.groups.of(u.slackUser.ID)
+}
+
+// userGroups caches the handles of the user groups of every user in
+// the workspace, requesting them to Slack again once they're older
+// than groupsTTL, or groupsRetry after failing to. It's safe for
+// concurrent use.
+type userGroups struct {
+	sync.Mutex
+	api     IClient
+	now     func() time.Time
+	expires time.Time
+	members map[string][]string
+}
+
+// newUserGroups returns an empty cache of the user groups requested
+// with `api`.
+func newUserGroups(api IClient) *userGroups {
+	return &userGroups{api: api, now: time.Now}
+}
+
+// of returns the handles of the user groups the user identified by
+// `id` belongs to. When they can't be requested to Slack, the error
+// is logged and the ones cached before, if any, are returned.
+func (g *userGroups) of(id string) []string {
+	g.Lock()
+	defer g.Unlock()
+	if !g.now().Before(g.expires) {
+		g.refresh()
+	}
+	return g.members[id]
+}
+
+// refresh requests the members of the user groups to Slack. It must
+// be called with the cache locked.
+func (g *userGroups) refresh() {
+	groups, err := g.api.GetUserGroups(slack.GetUserGroupsOptionIncludeUsers(true))
+	if err != nil {
+		log.Printf("Error getting the user groups: %v", err)
+		g.expires = g.now().Add(groupsRetry)
+		return
+	}
+	members := map[string][]string{}
+	for _, group := range groups {
+		for _, member := range group.Users {
+			members[member] = append(members[member], fmt.Sprintf("@%v", group.Handle))
+		}
+	}
+	g.members = members
+	g.expires = g.now().Add(groupsTTL)
+}
@@ -1,7 +1,10 @@
 package slack
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestNewUserFromID(t *testing.T) {
@@ -16,9 +19,87 @@ func TestNewUserFromID(t *testing.T) {
 			t.Logf("NewUserFromID errored for %v: %v", testID, err)
 			t.Fail()
 		}
+		if user.ID() != data[0] {
+			t.Logf("User ID was %v, instead of expected %v", user.ID(), data[0])
+			t.Fail()
+		}
 		if user.name != data[1] {
 			t.Logf("User name was %v, instead of expected %v", user.name, data[1])
 			t.Fail()
 		}
 	}
 }
+
+func TestUserGroups(t *testing.T) {
+	client := NewMockClient()
+	user, err := NewUserFromID("U000001", client)
+	if err != nil {
+		t.Fatalf("NewUserFromID errored: %v", err)
+	}
+
+	expected := []string{"@sre"}
+	if groups := user.Groups(); !reflect.DeepEqual(groups, expected) {
+		t.Logf("User groups were %v, instead of expected %v", groups, expected)
+		t.Fail()
+	}
+}
+
+func TestUserGroupsCache(t *testing.T) {
+	client := NewMockClient()
+	chat := NewChat(client, false, "me")
+	now := time.Now()
+	chat.groups.now = func() time.Time { return now }
+	user, err := chat.User("U000001")
+	if err != nil {
+		t.Fatalf("User errored: %v", err)
+	}
+	other, err := chat.User("U000001")
+	if err != nil {
+		t.Fatalf("User errored: %v", err)
+	}
+
+	tc := []struct {
+		name      string
+		user      *User
+		after     time.Duration
+		err       error
+		groups    []string
+		requested int
+	}{
+		{"First", user, 0, nil, []string{"@sre"}, 1},
+		{"Cached", user, time.Minute, nil, []string{"@sre"}, 1},
+		{"Shared by the users", other, 0, nil, []string{"@sre"}, 1},
+		{"Expired", user, groupsTTL, nil, []string{"@sre"}, 2},
+		{"Failing", user, groupsTTL, fmt.Errorf("ratelimited"), []string{"@sre"}, 3},
+		{"Failing backed off", user, time.Second, fmt.Errorf("ratelimited"), []string{"@sre"}, 3},
+		{"Recovered backed off", user, time.Second, nil, []string{"@sre"}, 3},
+		{"Retried", user, groupsRetry, nil, []string{"@sre"}, 4},
+	}
+	for _, data := range tc {
+		now = now.Add(data.after)
+		client.userGroupsErr = data.err
+		groups := data.user.Groups()
+		if !reflect.DeepEqual(groups, data.groups) || client.groupsRequested != data.requested {
+			t.Logf("%v: User groups were %v after %v requests, instead of expected %v after %v", data.name, groups, client.groupsRequested, data.groups, data.requested)
+			t.Fail()
+		}
+	}
+}
+
+func TestUserGroupsFirstFailure(t *testing.T) {
+	client := NewMockClient()
+	client.userGroupsErr = fmt.Errorf("ratelimited")
+	chat := NewChat(client, false, "me")
+	now := time.Now()
+	chat.groups.now = func() time.Time { return now }
+	user, err := chat.User("U000001")
+	if err != nil {
+		t.Fatalf("User errored: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if groups := user.Groups(); groups != nil || client.groupsRequested != 1 {
+			t.Errorf("User groups were %v after %v requests, instead of expected none after 1", groups, client.groupsRequested)
+		}
+	}
+}
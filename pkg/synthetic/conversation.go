@@ -2,5 +2,6 @@ package synthetic
 
 // Conversation is an interface to the conversation data.
 type Conversation interface {
+	ID() string
 	Name() string
 }
@@ -1,12 +1,34 @@
 package synthetic
 
+import "io"
+
 // Message is an interface for a chat message.
 type Message interface {
-	Reply(msg string, inThread bool)
+	ID() string
+	// Reply replies `msg` to the message, in its thread if
+	// `inThread` is true, and returns the Reply to change it later.
+	Reply(msg string, inThread bool) Reply
+	// ReplyEphemeral replies `msg` to the message, in its thread if
+	// `inThread` is true, visible only to the user who sent it.
+	ReplyEphemeral(msg string, inThread bool)
+	// ReplyResponse is like Reply, with the structured `response`.
+	ReplyResponse(response *Response, inThread bool) Reply
+	// Upload attaches the file `name` with the `content` of type
+	// `contentType`, like `text/plain`, to the thread of the message,
+	// with the `comment`, if not empty.
+	Upload(name, contentType string, content io.Reader, comment string) (Reply, error)
+	// WithOverflow returns the message replying with `overflow`
+	// when the replies are too long for the chat platform.
+	WithOverflow(overflow Overflow) Message
 	React(reaction string)
 	Unreact(reaction string)
 	Thread() bool
+	ThreadID() string
 	Mention() bool
+	// Unattended reports whether nobody follows the replies to the
+	// message, like for the scheduled commands, so nobody can be
+	// asked anything.
+	Unattended() bool
 	Text() string
 	User() User
 	Conversation() Conversation
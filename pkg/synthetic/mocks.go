@@ -1,8 +1,29 @@
 package synthetic
 
+import (
+	"io"
+	"sync"
+)
+
 // MockUser is a mock of a User.
 type MockUser struct {
-	name string
+	id     string
+	name   string
+	groups []string
+}
+
+// NewMockUser is the MockUser constructor.
+func NewMockUser(id, name string, groups ...string) MockUser {
+	return MockUser{
+		id:     id,
+		name:   name,
+		groups: groups,
+	}
+}
+
+// ID is a mock for User.ID() method.
+func (msu MockUser) ID() string {
+	return msu.id
 }
 
 // Name is a mock for User.Name() method.
@@ -10,24 +31,59 @@ func (msu MockUser) Name() string {
 	return msu.name
 }
 
+// Groups is a mock for User.Groups() method.
+func (msu MockUser) Groups() []string {
+	return msu.groups
+}
+
 // MockConversation is a mock for a Conversation
 type MockConversation struct {
+	id   string
 	name string
 }
 
+// NewMockConversation is the MockConversation constructor.
+func NewMockConversation(id, name string) MockConversation {
+	return MockConversation{
+		id:   id,
+		name: name,
+	}
+}
+
+// ID is a mock for Conversation.ID() method.
+func (msc MockConversation) ID() string {
+	return msc.id
+}
+
 // Name is a mock for Conversation.Name() method.
 func (msc MockConversation) Name() string {
 	return msc.name
 }
 
-// MockMessage is a mock for a Message.
+// MockMessage is a mock for a Message. It's safe for concurrent use.
 type MockMessage struct {
+	sync.Mutex
+	id           string
 	thread       bool
+	threadID     string
 	mention      bool
+	unattended   bool
 	text         string
 	user         MockUser
 	conversation MockConversation
-	replies      []string
+	replies      []*MockReply
+	ephemeral    []string
+	reactions    []string
+	uploads      []MockUpload
+	overflow     Overflow
+}
+
+// MockUpload is a file uploaded to a MockMessage.
+type MockUpload struct {
+	Name        string
+	ContentType string
+	Content     string
+	Comment     string
 }
 
 // NewMockMessage is the MockMessage constructor.
@@ -35,22 +91,102 @@ func NewMockMessage(input string, mention bool) *MockMessage {
 	return &MockMessage{
 		text:    input,
 		mention: mention,
-		replies: []string{},
+		replies: []*MockReply{},
 	}
 }
 
-// Replies returns the replies received by the MockMessage.
+// Replies returns the text of the replies received by the
+// MockMessage, as edited, without the deleted ones.
 func (msm *MockMessage) Replies() []string {
-	return msm.replies
+	msm.Lock()
+	defer msm.Unlock()
+	replies := []string{}
+	for _, reply := range msm.replies {
+		if !reply.deleted {
+			replies = append(replies, reply.text)
+		}
+	}
+	return replies
 }
 
 // Reply is a mock for Message.Reply() method.
-func (msm *MockMessage) Reply(msg string, inThread bool) {
-	msm.replies = append(msm.replies, msg)
+func (msm *MockMessage) Reply(msg string, inThread bool) Reply {
+	msm.Lock()
+	defer msm.Unlock()
+	reply := &MockReply{message: msm, text: msg}
+	msm.replies = append(msm.replies, reply)
+	return reply
+}
+
+// Ephemeral returns the replies received by the MockMessage visible
+// only to its user, which are in Replies() too.
+func (msm *MockMessage) Ephemeral() []string {
+	msm.Lock()
+	defer msm.Unlock()
+	return append([]string{}, msm.ephemeral...)
+}
+
+// ReplyEphemeral is a mock for Message.ReplyEphemeral() method.
+func (msm *MockMessage) ReplyEphemeral(msg string, inThread bool) {
+	msm.Reply(msg, inThread)
+	msm.Lock()
+	defer msm.Unlock()
+	msm.ephemeral = append(msm.ephemeral, msg)
+}
+
+// ReplyResponse is a mock for Message.ReplyResponse() method,
+// replying with the plain text of `response`.
+func (msm *MockMessage) ReplyResponse(response *Response, inThread bool) Reply {
+	return msm.Reply(response.PlainText(), inThread)
+}
+
+// Uploads returns the files uploaded to the MockMessage.
+func (msm *MockMessage) Uploads() []MockUpload {
+	msm.Lock()
+	defer msm.Unlock()
+	return append([]MockUpload{}, msm.uploads...)
+}
+
+// Upload is a mock for Message.Upload() method.
+func (msm *MockMessage) Upload(name, contentType string, content io.Reader, comment string) (Reply, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+	msm.Lock()
+	defer msm.Unlock()
+	msm.uploads = append(msm.uploads, MockUpload{Name: name, ContentType: contentType, Content: string(data), Comment: comment})
+	return &MockReply{message: msm}, nil
+}
+
+// WithOverflow is a mock for Message.WithOverflow() method, setting
+// the value returned by Overflow().
+func (msm *MockMessage) WithOverflow(overflow Overflow) Message {
+	msm.Lock()
+	defer msm.Unlock()
+	msm.overflow = overflow
+	return msm
+}
+
+// Overflow returns the last overflow given to WithOverflow().
+func (msm *MockMessage) Overflow() Overflow {
+	msm.Lock()
+	defer msm.Unlock()
+	return msm.overflow
+}
+
+// Reactions returns the reactions added to the MockMessage, in order.
+func (msm *MockMessage) Reactions() []string {
+	msm.Lock()
+	defer msm.Unlock()
+	return append([]string{}, msm.reactions...)
 }
 
 // React is a mock for Message.React() method.
 func (msm *MockMessage) React(reaction string) {
+	msm.Lock()
+	defer msm.Unlock()
+	msm.reactions = append(msm.reactions, reaction)
 }
 
 // Unreact is a mock for Message.Unreact() method.
@@ -62,16 +198,46 @@ func (msm *MockMessage) ClearMention() string {
 	return msm.text
 }
 
+// ID is a mock for Message.ID() method.
+func (msm *MockMessage) ID() string {
+	return msm.id
+}
+
+// SetID sets the value returned by ID().
+func (msm *MockMessage) SetID(id string) {
+	msm.id = id
+}
+
 // Thread is a mock for Message.Thread() method.
 func (msm *MockMessage) Thread() bool {
 	return msm.thread
 }
 
+// ThreadID is a mock for Message.ThreadID() method.
+func (msm *MockMessage) ThreadID() string {
+	return msm.threadID
+}
+
+// SetThreadID sets the value returned by ThreadID().
+func (msm *MockMessage) SetThreadID(threadID string) {
+	msm.threadID = threadID
+}
+
 // Mention is a mock for Message.Mention() method.
 func (msm *MockMessage) Mention() bool {
 	return msm.mention
 }
 
+// Unattended is a mock for Message.Unattended() method.
+func (msm *MockMessage) Unattended() bool {
+	return msm.unattended
+}
+
+// SetUnattended sets the value returned by Unattended().
+func (msm *MockMessage) SetUnattended(unattended bool) {
+	msm.unattended = unattended
+}
+
 // Text is a mock for Message.Text() method.
 func (msm *MockMessage) Text() string {
 	return msm.text
@@ -82,7 +248,72 @@ func (msm *MockMessage) User() User {
 	return msm.user
 }
 
+// SetUser sets the value returned by User().
+func (msm *MockMessage) SetUser(user MockUser) {
+	msm.user = user
+}
+
+// SetConversation sets the value returned by Conversation().
+func (msm *MockMessage) SetConversation(conversation MockConversation) {
+	msm.conversation = conversation
+}
+
 // Conversation is a mock for Message.Conversation() method.
 func (msm *MockMessage) Conversation() Conversation {
 	return msm.conversation
 }
+
+// MockReply is a mock for a Reply, changing the replies of its
+// MockMessage.
+type MockReply struct {
+	message *MockMessage
+	text    string
+	deleted bool
+}
+
+// Edit is a mock for Reply.Edit() method.
+func (msr *MockReply) Edit(msg string) error {
+	msr.message.Lock()
+	defer msr.message.Unlock()
+	msr.text = msg
+	return nil
+}
+
+// Delete is a mock for Reply.Delete() method.
+func (msr *MockReply) Delete() error {
+	msr.message.Lock()
+	defer msr.message.Unlock()
+	msr.deleted = true
+	return nil
+}
+
+// MockReaction is a mock for a Reaction.
+type MockReaction struct {
+	name      string
+	messageID string
+	user      MockUser
+}
+
+// NewMockReaction is the MockReaction constructor.
+func NewMockReaction(name, messageID string, user MockUser) *MockReaction {
+	return &MockReaction{
+		name:      name,
+		messageID: messageID,
+		user:      user,
+	}
+}
+
+// Name is a mock for Reaction.Name() method.
+func (msr *MockReaction) Name() string {
+	return msr.name
+}
+
+// MessageID is a mock for Reaction.MessageID() method.
+func (msr *MockReaction) MessageID() string {
+	return msr.messageID
+}
+
+// User is a mock for Reaction.User() method.
+func (msr *MockReaction) User() User {
+	return msr.user
+}
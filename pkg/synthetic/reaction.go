@@ -0,0 +1,9 @@
+package synthetic
+
+// Reaction is an interface for a reaction added to a message.
+type Reaction interface {
+	Name() string
+	// MessageID is the ID of the message the reaction was added to.
+	MessageID() string
+	User() User
+}
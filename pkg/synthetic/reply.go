@@ -0,0 +1,21 @@
+package synthetic
+
+// Reply is a message the bot replied with, which can be changed
+// afterwards, like to show the progress of a command in place.
+type Reply interface {
+	// Edit replaces the text of the reply with `msg`.
+	Edit(msg string) error
+	// Delete removes the reply.
+	Delete() error
+}
+
+// Overflow is how a reply too long for the chat platform is sent.
+type Overflow string
+
+const (
+	// OverflowSplit splits the reply at line boundaries into several
+	// messages.
+	OverflowSplit Overflow = "split"
+	// OverflowSnippet uploads the reply as a text snippet.
+	OverflowSnippet Overflow = "snippet"
+)
@@ -0,0 +1,112 @@
+package synthetic
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// Response is a structured reply, made of Blocks under an optional
+// Header. Each platform renders it the best it can, falling back to
+// its PlainText.
+type Response struct {
+	Header string
+	Blocks []Block
+}
+
+// PlainText returns the Response as plain text, with the same
+// Markdown as the text replies.
+func (r *Response) PlainText() string {
+	lines := []string{}
+	if r.Header != "" {
+		lines = append(lines, fmt.Sprintf("*%s*", r.Header))
+	}
+	for _, block := range r.Blocks {
+		lines = append(lines, block.PlainText())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Block is a part of a Response.
+type Block interface {
+	// PlainText returns the Block as plain text.
+	PlainText() string
+}
+
+// Section is a paragraph of Markdown text.
+type Section string
+
+// PlainText returns the text of the Section.
+func (s Section) PlainText() string {
+	return string(s)
+}
+
+// Field is a value with a name, like `Status: running`.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Fields are Fields shown together, like in columns.
+type Fields []Field
+
+// PlainText returns the Fields one per line.
+func (f Fields) PlainText() string {
+	lines := []string{}
+	for _, field := range f {
+		lines = append(lines, fmt.Sprintf("*%s*: %s", field.Name, field.Value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Code is a block of preformatted text, like logs.
+type Code string
+
+// PlainText returns the Code in a code block.
+func (c Code) PlainText() string {
+	return fmt.Sprintf("```\n%s\n```", strings.TrimRight(string(c), "\n"))
+}
+
+// Table is a table of text, with a row of Columns on top.
+type Table struct {
+	Columns []string
+	Rows    [][]string
+}
+
+// PlainText returns the Table in a code block, with its columns
+// aligned.
+func (t *Table) PlainText() string {
+	table := &strings.Builder{}
+	w := tabwriter.NewWriter(table, 0, 0, 2, ' ', 0)
+	for _, row := range append([][]string{t.Columns}, t.Rows...) {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	return Code(table.String()).PlainText()
+}
+
+// Link is a link to URL, shown as Text.
+type Link struct {
+	Text string
+	URL  string
+}
+
+// Links are Links shown one per line.
+type Links []Link
+
+// PlainText returns the Links one per line, with their URLs.
+func (l Links) PlainText() string {
+	lines := []string{}
+	for _, link := range l {
+		lines = append(lines, fmt.Sprintf("%s: %s", link.Text, link.URL))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Context is a footnote of a Response, like when it was generated.
+type Context string
+
+// PlainText returns the text of the Context in italics.
+func (c Context) PlainText() string {
+	return fmt.Sprintf("_%s_", string(c))
+}
@@ -0,0 +1,71 @@
+package synthetic
+
+import (
+	"testing"
+)
+
+func TestPlainText(t *testing.T) {
+	tt := map[string]struct {
+		block    Block
+		expected string
+	}{
+		"Section": {
+			block:    Section("Build *deploy* started"),
+			expected: "Build *deploy* started",
+		},
+		"Fields": {
+			block:    Fields{{Name: "Status", Value: "running"}, {Name: "Duration", Value: "2m"}},
+			expected: "*Status*: running\n*Duration*: 2m",
+		},
+		"No fields": {
+			block:    Fields{},
+			expected: "",
+		},
+		"Code": {
+			block:    Code("line 1\nline 2\n\n"),
+			expected: "```\nline 1\nline 2\n```",
+		},
+		"Table": {
+			block: &Table{
+				Columns: []string{"NAME", "STATUS"},
+				Rows:    [][]string{{"api-1", "Running"}, {"worker", "CrashLoopBackOff"}},
+			},
+			expected: "```\nNAME    STATUS\napi-1   Running\nworker  CrashLoopBackOff\n```",
+		},
+		"Table without rows": {
+			block:    &Table{Columns: []string{"NAME", "STATUS"}},
+			expected: "```\nNAME  STATUS\n```",
+		},
+		"Links": {
+			block:    Links{{Text: "Build #3", URL: "https://jenkins.example.com/job/deploy/3"}, {Text: "Logs", URL: "https://logs.example.com"}},
+			expected: "Build #3: https://jenkins.example.com/job/deploy/3\nLogs: https://logs.example.com",
+		},
+		"Context": {
+			block:    Context("Updated at 10:30"),
+			expected: "_Updated at 10:30_",
+		},
+		"Response": {
+			block: &Response{
+				Header: "deploy #3",
+				Blocks: []Block{
+					Fields{{Name: "Result", Value: "SUCCESS"}},
+					Links{{Text: "Console", URL: "https://jenkins.example.com/job/deploy/3/console"}},
+					Context("Took 2m"),
+				},
+			},
+			expected: "*deploy #3*\n*Result*: SUCCESS\nConsole: https://jenkins.example.com/job/deploy/3/console\n_Took 2m_",
+		},
+		"Response without header": {
+			block:    &Response{Blocks: []Block{Section("Nothing to do")}},
+			expected: "Nothing to do",
+		},
+	}
+
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			if result := tc.block.PlainText(); result != tc.expected {
+				t.Errorf("wrong plain text %q should be %q", result, tc.expected)
+			}
+		})
+	}
+}
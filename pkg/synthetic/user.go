@@ -2,5 +2,29 @@ package synthetic
 
 // User is an interface to the user data.
 type User interface {
+	ID() string
 	Name() string
+	// Groups returns the names of the user groups the user belongs
+	// to.
+	Groups() []string
+}
+
+// SystemUser is the User sending the commands the bot runs by
+// itself, like the scheduled ones. Whoever sends them checks the
+// rights of the users they run on behalf of.
+type SystemUser struct{}
+
+// ID returns the ID of the system user.
+func (SystemUser) ID() string {
+	return "system"
+}
+
+// Name returns the name of the system user.
+func (SystemUser) Name() string {
+	return "@system"
+}
+
+// Groups returns no groups, as the system user isn't in any.
+func (SystemUser) Groups() []string {
+	return nil
 }
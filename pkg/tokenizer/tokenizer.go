@@ -0,0 +1,131 @@
+package tokenizer
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// smartQuotes replaces the typographic quotes, like the ones Slack
+// may send, by the plain ones.
+var smartQuotes = strings.NewReplacer("“", "\"", "”", "\"", "‘", "'", "’", "'")
+
+// Token is a token of a text, and whether any of it was quoted or
+// escaped, so it's taken literally.
+type Token struct {
+	Value  string
+	Quoted bool
+}
+
+// Scan splits `input` into Tokens separated by spaces, like a shell
+// does. Quoted text, in single or double quotes, is part of a Token
+// even with spaces, and the quotes are removed, so `INDEX="users
+// ducks"` is `INDEX=users ducks`. A backslash escapes the next
+// character, except within single quotes. Typographic quotes are taken
+// as the plain ones. A `;` neither quoted nor escaped is a Token by
+// itself. It returns an error when a quote isn't closed.
+func Scan(input string) ([]Token, error) {
+	tokens := []Token{}
+	token := &strings.Builder{}
+	inToken := false
+	quoted := false
+	quote := rune(0)
+	escaped := false
+	end := func() {
+		if inToken {
+			tokens = append(tokens, Token{Value: token.String(), Quoted: quoted})
+		}
+		token.Reset()
+		inToken = false
+		quoted = false
+	}
+	for _, c := range smartQuotes.Replace(input) {
+		switch {
+		case escaped:
+			token.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			inToken = true
+			quoted = true
+			escaped = true
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			token.WriteRune(c)
+		case c == '"' || c == '\'':
+			inToken = true
+			quoted = true
+			quote = c
+		case c == ';':
+			end()
+			tokens = append(tokens, Token{Value: ";"})
+		case unicode.IsSpace(c):
+			end()
+		default:
+			inToken = true
+			token.WriteRune(c)
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("there's an unbalanced `%c` quote. Close it, or escape it like `\\%c`", quote, quote)
+	}
+	if escaped {
+		token.WriteRune('\\')
+	}
+	end()
+	return tokens, nil
+}
+
+// Split returns the values of the Tokens of `input`, as returned by
+// Scan.
+func Split(input string) ([]string, error) {
+	tokens, err := Scan(input)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, len(tokens))
+	for i, token := range tokens {
+		values[i] = token.Value
+	}
+	return values, nil
+}
+
+// Join joins `values` with spaces, quoting the ones that need it, so
+// Split returns them back.
+func Join(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = Quote(value)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// JoinTokens joins the values of `tokens` like Join, but for the
+// chain operators not quoted, like `&&`, which are kept as such.
+func JoinTokens(tokens []Token) string {
+	joined := make([]string, len(tokens))
+	for i, token := range tokens {
+		switch {
+		case !token.Quoted && (token.Value == "&&" || token.Value == "||" || token.Value == ";"):
+			joined[i] = token.Value
+		default:
+			joined[i] = Quote(token.Value)
+		}
+	}
+	return strings.Join(joined, " ")
+}
+
+// Quote returns `value` in double quotes, escaping the double quotes
+// and backslashes in it, if it's empty, has spaces, quotes,
+// backslashes or `;`, or it's a chain operator like `&&`. Otherwise it
+// returns `value` as is.
+func Quote(value string) string {
+	special := func(c rune) bool {
+		return unicode.IsSpace(c) || strings.ContainsRune("\"'\\;“”‘’", c)
+	}
+	if value != "" && value != "&&" && value != "||" && strings.IndexFunc(value, special) < 0 {
+		return value
+	}
+	escaper := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "“", "\\“", "”", "\\”")
+	return fmt.Sprintf("\"%s\"", escaper.Replace(value))
+}
@@ -0,0 +1,166 @@
+package tokenizer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tt := []struct {
+		input  string
+		result []string
+		err    string
+	}{
+		{
+			input:  "",
+			result: []string{},
+		},
+		{
+			input:  "build deploy",
+			result: []string{"build", "deploy"},
+		},
+		{
+			input:  "build  deploy      INDEX=users",
+			result: []string{"build", "deploy", "INDEX=users"},
+		},
+		{
+			input:  "build  deploy      INDEX=\"users\"",
+			result: []string{"build", "deploy", "INDEX=users"},
+		},
+		{
+			input:  "build  deploy      INDEX=\"users ducks\"",
+			result: []string{"build", "deploy", "INDEX=users ducks"},
+		},
+		{
+			input:  "build deploy INDEX='users \"ducks\"'",
+			result: []string{"build", "deploy", "INDEX=users \"ducks\""},
+		},
+		{
+			input:  "build deploy INDEX=“users ducks” NAME=‘bob’",
+			result: []string{"build", "deploy", "INDEX=users ducks", "NAME=bob"},
+		},
+		{
+			input:  "build deploy MSG=it\\'s\\ done PATH=\"C:\\\\tmp\" \\",
+			result: []string{"build", "deploy", "MSG=it's done", "PATH=C:\\tmp", "\\"},
+		},
+		{
+			input:  "echo '' \"\"",
+			result: []string{"echo", "", ""},
+		},
+		{
+			input: "build deploy INDEX=\"users ducks",
+			err:   "there's an unbalanced `\"` quote. Close it, or escape it like `\\\"`",
+		},
+		{
+			input: "hello, I don’t know",
+			err:   "there's an unbalanced `'` quote. Close it, or escape it like `\\'`",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.input, func(t *testing.T) {
+			result, err := Split(tc.input)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("expected error `%s` but got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(result, tc.result) {
+				t.Errorf("expected %q but got %q", tc.result, result)
+			}
+		})
+	}
+}
+
+func TestScan(t *testing.T) {
+	tt := []struct {
+		input  string
+		result []Token
+	}{
+		{
+			input:  "build a; build b",
+			result: []Token{{"build", false}, {"a", false}, {";", false}, {"build", false}, {"b", false}},
+		},
+		{
+			input:  "build a && say \";\" '&&' \\;",
+			result: []Token{{"build", false}, {"a", false}, {"&&", false}, {"say", false}, {";", true}, {"&&", true}, {";", true}},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.input, func(t *testing.T) {
+			result, err := Scan(tc.input)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(result, tc.result) {
+				t.Errorf("expected %v but got %v", tc.result, result)
+			}
+		})
+	}
+}
+
+func TestJoin(t *testing.T) {
+	tt := []struct {
+		tokens []string
+		result string
+	}{
+		{
+			tokens: []string{"build", "deploy", "INDEX=users"},
+			result: "build deploy INDEX=users",
+		},
+		{
+			tokens: []string{"build", "deploy", "INDEX=users ducks", ""},
+			result: "build deploy \"INDEX=users ducks\" \"\"",
+		},
+		{
+			tokens: []string{"say", "it's \"done\"", "C:\\tmp"},
+			result: "say \"it's \\\"done\\\"\" \"C:\\\\tmp\"",
+		},
+		{
+			tokens: []string{"say", "&&", "a;b", "||"},
+			result: "say \"&&\" \"a;b\" \"||\"",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.result, func(t *testing.T) {
+			result := Join(tc.tokens)
+			if result != tc.result {
+				t.Errorf("expected `%s` but got `%s`", tc.result, result)
+			}
+			tokens, err := Split(result)
+			if err != nil || !reflect.DeepEqual(tokens, tc.tokens) {
+				t.Errorf("expected %q splitting `%s` but got %q, %v", tc.tokens, result, tokens, err)
+			}
+		})
+	}
+}
+
+func TestJoinTokens(t *testing.T) {
+	tt := map[string]struct {
+		input  string
+		result string
+	}{
+		"Operators": {
+			input:  "build a && build b || say \"it's done\"; list",
+			result: "build a && build b || say \"it's done\" ; list",
+		},
+		"Quoted operators": {
+			input:  "say '&&' \\|| \";\"",
+			result: "say \"&&\" \"||\" \";\"",
+		},
+	}
+	for testID, tc := range tt {
+		t.Run(testID, func(t *testing.T) {
+			tokens, err := Scan(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result := JoinTokens(tokens); result != tc.result {
+				t.Errorf("expected `%s` but got `%s`", tc.result, result)
+			}
+		})
+	}
+}